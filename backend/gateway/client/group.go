@@ -54,8 +54,9 @@ func (c *GroupClientImpl) GetGroup(authUsername string, id int) (*Group, error)
 	return &group, nil
 }
 
+// GetGroupsOfUser only fills in the IDs, archived groups included
 func (c *GroupClientImpl) GetGroupsOfUser(authUsername string, username string) ([]*Group, error) {
-	req, err := http.NewRequest("GET", c.groupUrl+"/api/group?username="+username, nil)
+	req, err := http.NewRequest("GET", c.groupUrl+"/api/group/ids?username="+username, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -72,10 +73,14 @@ func (c *GroupClientImpl) GetGroupsOfUser(authUsername string, username string)
 		return nil, fmt.Errorf("failed to get groups of user: %s", resp.Status)
 	}
 
-	var groups []*Group
-	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+	var groupIDs []int
+	if err := json.NewDecoder(resp.Body).Decode(&groupIDs); err != nil {
 		return nil, err
 	}
 
+	groups := make([]*Group, len(groupIDs))
+	for i, id := range groupIDs {
+		groups[i] = &Group{ID: id}
+	}
 	return groups, nil
 }
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ChatMessage struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	GroupID   int       `json:"group_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateMessageRequest struct {
+	Content          string          `json:"content"`
+	Attachments      json.RawMessage `json:"attachments,omitempty"`
+	Encrypted        json.RawMessage `json:"encrypted,omitempty"`
+	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
+}
+
+type MessageClient interface {
+	SendMessage(string, int, string, json.RawMessage, json.RawMessage, *int) (*ChatMessage, error)
+}
+
+type MessageClientImpl struct {
+	messageUrl string
+}
+
+func NewMessageClient(messageUrl string) MessageClient {
+	return &MessageClientImpl{
+		messageUrl: messageUrl,
+	}
+}
+
+func (c *MessageClientImpl) SendMessage(authUsername string, groupID int, content string, attachments json.RawMessage, encrypted json.RawMessage, replyToMessageID *int) (*ChatMessage, error) {
+	body, err := json.Marshal(&CreateMessageRequest{Content: content, Attachments: attachments, Encrypted: encrypted, ReplyToMessageID: replyToMessageID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.messageUrl+"/api/message/group/"+strconv.Itoa(groupID), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Username", authUsername)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to send message: %s", resp.Status)
+	}
+
+	var msg ChatMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
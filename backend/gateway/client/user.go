@@ -23,6 +23,7 @@ type UserCreateRequest struct {
 type UserClient interface {
 	GetUser(string) (*User, error)
 	CreateUser(string, string) (*User, error)
+	GetFriendUsernames(string) ([]string, error)
 }
 
 type UserClientImpl struct {
@@ -77,3 +78,29 @@ func (c *UserClientImpl) CreateUser(username string, displayName string) (*User,
 
 	return &user, nil
 }
+
+func (c *UserClientImpl) GetFriendUsernames(username string) ([]string, error) {
+	req, err := http.NewRequest("GET", c.userUrl+"/api/user/friends/usernames", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Username", username)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get friends: %s", resp.Status)
+	}
+
+	var usernames []string
+	if err := json.NewDecoder(resp.Body).Decode(&usernames); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
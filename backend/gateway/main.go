@@ -33,9 +33,18 @@ func main() {
 	}))
 
 	auth.MakeAuthHandler(app, MakeAuthRepository(), MakeUserClient())
-	websocket.MakeHandler(app, MakeGroupClient())
+	websocket.MakeHandler(app, MakeGroupClient(), MakeUserClient(), MakeMessageClient())
 	MakeGatewayHandler(app)
 
+	// Socket events pushed by the other services, on a port that is not published
+	internalApp := gin.New()
+	websocket.MakeInternalHandler(internalApp, MakeGroupClient())
+	go func() {
+		if err := internalApp.Run(":8081"); err != nil {
+			panic(err)
+		}
+	}()
+
 	if err := app.Run(":8080"); err != nil {
 		panic(err)
 	}
@@ -49,6 +58,10 @@ func MakeGroupClient() client.GroupClient {
 	return client.NewGroupClient(messageServiceURL)
 }
 
+func MakeMessageClient() client.MessageClient {
+	return client.NewMessageClient(messageServiceURL)
+}
+
 func MakeAuthRepository() *auth.Repository {
 	authDsn := "host=authdb user=postgres password=root dbname=auth port=5432 sslmode=disable"
 	authRepo, err := auth.NewAuthRepository(authDsn)
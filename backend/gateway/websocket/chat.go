@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gateway/client"
+)
+
+// handleSocketChat persists a chat message sent over the socket through the message service,
+// which broadcasts it back through /ws/message, and acknowledges the client's temp ID
+func handleSocketChat(c *Client, chat ChatPayload, groupClient client.GroupClient, messageClient client.MessageClient) {
+	ack := AckPayload{
+		TempID:  chat.TempID,
+		GroupID: chat.GroupID,
+	}
+
+	if chat.Content == "" && len(chat.Attachments) == 0 && len(chat.Encrypted) == 0 {
+		ack.Error = "Message content is empty"
+		sendAck(c, ack)
+		return
+	}
+
+	if !c.InGroup(chat.GroupID) {
+		if err := syncGroups(c, groupClient); err != nil {
+			fmt.Println("Failed to update groups:", err)
+		}
+		if !c.InGroup(chat.GroupID) {
+			ack.Error = "Group not found or user not in group"
+			sendAck(c, ack)
+			return
+		}
+	}
+
+	msg, err := messageClient.SendMessage(c.Username, chat.GroupID, chat.Content, chat.Attachments, chat.Encrypted, chat.ReplyToMessageID)
+	if err != nil {
+		fmt.Println("Failed to send chat message:", err)
+		ack.Error = "Failed to send message"
+		sendAck(c, ack)
+		return
+	}
+
+	ack.MessageID = msg.ID
+	ack.CreatedAt = &msg.CreatedAt
+	sendAck(c, ack)
+}
+
+func sendAck(c *Client, ack AckPayload) {
+	payload, err := json.Marshal(ack)
+	if err != nil {
+		fmt.Println("Failed to marshal ack payload:", err)
+		return
+	}
+	msg := Message{
+		Type:    MessageAck,
+		Payload: json.RawMessage(payload),
+	}
+	if err := c.WriteJSON(msg); err != nil {
+		fmt.Println("Failed to send ack through socket:", err)
+	}
+}
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -13,12 +14,57 @@ const (
 	MessageAuth         MessageType = "auth"
 	MessageChat         MessageType = "chat"
 	MessageNotification MessageType = "notification"
+	MessageTyping       MessageType = "typing"
+	MessagePresence     MessageType = "presence"
+	MessageAck          MessageType = "ack"
+	MessageRead         MessageType = "read"
+	MessageEdit         MessageType = "edit"
+	MessageDelete       MessageType = "delete"
+	MessageReaction     MessageType = "reaction"
+	MessageExpire       MessageType = "expire"
+	MessageLinkPreview  MessageType = "linkPreview"
+)
+
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceOffline PresenceStatus = "offline"
 )
 
 type Client struct {
 	Username string
 	Conn     *websocket.Conn
-	Groups   []int
+	Groups   []int // guarded by hubLock
+	Friends  []string
+
+	// lastTyping is only touched by the client's own read loop
+	lastTyping map[int]time.Time
+	writeLock  sync.Mutex
+}
+
+// gorilla connections support a single concurrent writer, so every write goes through these
+func (c *Client) WriteJSON(v interface{}) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+func (c *Client) WriteMessage(data []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *Client) InGroup(groupID int) bool {
+	hubLock.RLock()
+	defer hubLock.RUnlock()
+	for _, id := range c.Groups {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
 }
 
 type Message struct {
@@ -31,11 +77,27 @@ type AuthPayload struct {
 }
 
 type ChatPayload struct {
+	TempID    string    `json:"tempId,omitempty"`
 	MessageID int       `json:"messageId"`
 	Username  string    `json:"username"`
 	GroupID   int       `json:"groupId"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"createdAt"`
+	System    bool      `json:"system,omitempty"`
+
+	// Silent is set for recipients who muted the group, so clients skip the alert
+	Silent         bool     `json:"silent,omitempty"`
+	MutedUsernames []string `json:"mutedUsernames,omitempty"`
+
+	// Validated and shaped by the message service, relayed as is
+	Protocol    string          `json:"protocol,omitempty"`
+	Encrypted   json.RawMessage `json:"encrypted,omitempty"`
+	Attachments json.RawMessage `json:"attachments,omitempty"`
+	LinkPreview json.RawMessage `json:"linkPreview,omitempty"`
+	ReplyTo     json.RawMessage `json:"replyTo,omitempty"`
+
+	// Only read from clients sending over the socket, the broadcast quotes the message in ReplyTo
+	ReplyToMessageID *int `json:"replyToMessageId,omitempty"`
 }
 
 type NotificationPayload struct {
@@ -47,3 +109,64 @@ type NotificationPayload struct {
 	Read      bool      `json:"read"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type TypingPayload struct {
+	Username string `json:"username"`
+	GroupID  int    `json:"groupId"`
+	Typing   bool   `json:"typing"`
+}
+
+type ReadPayload struct {
+	Username  string `json:"username"`
+	GroupID   int    `json:"groupId"`
+	MessageID int    `json:"messageId"`
+}
+
+type EditPayload struct {
+	MessageID int       `json:"messageId"`
+	Username  string    `json:"username"`
+	GroupID   int       `json:"groupId"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+type DeletePayload struct {
+	MessageID int    `json:"messageId"`
+	Username  string `json:"username"`
+	GroupID   int    `json:"groupId"`
+}
+
+// An empty Type means the reaction was removed
+type ReactionPayload struct {
+	MessageID int    `json:"messageId"`
+	Username  string `json:"username"`
+	GroupID   int    `json:"groupId"`
+	Type      string `json:"type"`
+}
+
+// LinkPreviewPayload delivers a preview the message service fetched after the message was sent
+type LinkPreviewPayload struct {
+	MessageID   int             `json:"messageId"`
+	GroupID     int             `json:"groupId"`
+	LinkPreview json.RawMessage `json:"linkPreview"`
+}
+
+// ExpirePayload lists messages the group's retention policy removed
+type ExpirePayload struct {
+	GroupID    int   `json:"groupId"`
+	MessageIDs []int `json:"messageIds"`
+}
+
+type PresencePayload struct {
+	Username string         `json:"username"`
+	Status   PresenceStatus `json:"status"`
+	LastSeen *time.Time     `json:"lastSeen,omitempty"`
+}
+
+type AckPayload struct {
+	TempID    string     `json:"tempId"`
+	MessageID int        `json:"messageId,omitempty"`
+	GroupID   int        `json:"groupId"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"gateway/client"
 	"gateway/internal"
@@ -15,21 +17,56 @@ import (
 var clients = make(map[string]*Client)
 var groupMembers = make(map[int][]string)
 
+// hubLock guards clients, groupMembers and lastSeen
+var hubLock sync.RWMutex
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
-func MakeHandler(app *gin.Engine, groupClient client.GroupClient) {
+func MakeHandler(app *gin.Engine, groupClient client.GroupClient, userClient client.UserClient, messageClient client.MessageClient) {
 	app.GET("/ws", func(c *gin.Context) {
-		handleWebsocket(c, groupClient)
+		handleWebsocket(c, groupClient, userClient, messageClient)
+	})
+
+	app.GET("/api/presence", internal.AuthMiddleware(), func(c *gin.Context) {
+		handlePresence(c, userClient)
 	})
+}
 
+// MakeInternalHandler registers the callbacks the other services push socket events through. They
+// trust X-Username, so app must only listen where the public cannot reach it
+func MakeInternalHandler(app *gin.Engine, groupClient client.GroupClient) {
 	app.POST("/ws/message", func(c *gin.Context) {
 		handleChatMessage(c, groupClient)
 	})
 
+	app.POST("/ws/read", func(c *gin.Context) {
+		handleReadMessage(c, groupClient)
+	})
+
+	app.POST("/ws/edit", func(c *gin.Context) {
+		handleEditMessage(c, groupClient)
+	})
+
+	app.POST("/ws/delete", func(c *gin.Context) {
+		handleDeleteMessage(c, groupClient)
+	})
+
+	app.POST("/ws/reaction", func(c *gin.Context) {
+		handleReactionMessage(c, groupClient)
+	})
+
+	app.POST("/ws/link-preview", func(c *gin.Context) {
+		handleLinkPreviewMessage(c, groupClient)
+	})
+
+	app.POST("/ws/expire", func(c *gin.Context) {
+		handleExpireMessage(c)
+	})
+
 	app.POST("/ws/noti", func(c *gin.Context) {
 		handleNotificationMessage(c)
 	})
@@ -48,34 +85,167 @@ func handleChatMessage(c *gin.Context, groupClient client.GroupClient) {
 		return
 	}
 	body.Username = username
-
-	fmt.Printf("[CHAT] %v: %v\n", len(clients), clients)
-	if _, ok := clients[body.Username]; !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not connected"})
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
 		return
 	}
 
+	muted := make(map[string]bool, len(body.MutedUsernames))
+	for _, u := range body.MutedUsernames {
+		muted[u] = true
+	}
+	body.MutedUsernames = nil
+
+	hubLock.RLock()
+	fmt.Printf("[CHAT] %v: %v\n", len(clients), clients)
+	sender, connected := clients[body.Username]
+	hubLock.RUnlock()
+
 	payload, err := json.Marshal(body)
 	if err != nil {
 		fmt.Println("Failed to marshal chat payload:", err)
 		return
 	}
-	msg := Message{
-		Type:    MessageChat,
-		Payload: json.RawMessage(payload),
+	body.Silent = true
+	silentPayload, err := json.Marshal(body)
+	if err != nil {
+		fmt.Println("Failed to marshal chat payload:", err)
+		return
+	}
+	msg := func(recipient string) interface{} {
+		if muted[recipient] {
+			return Message{Type: MessageChat, Payload: json.RawMessage(silentPayload)}
+		}
+		return Message{Type: MessageChat, Payload: json.RawMessage(payload)}
 	}
 
-	if !tryBroadcastToGroup(body.Username, body.GroupID, msg) {
-		_, err := updateGroups(groupClient, username, username)
-		if err != nil {
-			fmt.Println("Failed to update groups:", err)
+	if !tryBroadcastToGroupWith(body.Username, body.GroupID, msg) {
+		// messages sent over REST may come from users without an open socket
+		if !connected {
 			return
 		}
-		if !tryBroadcastToGroup(body.Username, body.GroupID, msg) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found or user not in group"})
+		if err := syncGroups(sender, groupClient); err != nil {
+			fmt.Println("Failed to update groups:", err)
 			return
 		}
+		tryBroadcastToGroupWith(body.Username, body.GroupID, msg)
+	}
+}
+
+func handleReadMessage(c *gin.Context, groupClient client.GroupClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body ReadPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Username = username
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
+		return
+	}
+
+	relayToGroup(username, body.GroupID, MessageRead, body)
+}
+
+func handleEditMessage(c *gin.Context, groupClient client.GroupClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body EditPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Username = username
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
+		return
+	}
+
+	relayToGroup(username, body.GroupID, MessageEdit, body)
+}
+
+func handleDeleteMessage(c *gin.Context, groupClient client.GroupClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body DeletePayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Username = username
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
+		return
 	}
+
+	relayToGroup(username, body.GroupID, MessageDelete, body)
+}
+
+func handleReactionMessage(c *gin.Context, groupClient client.GroupClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body ReactionPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Username = username
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
+		return
+	}
+
+	relayToGroup(username, body.GroupID, MessageReaction, body)
+}
+
+func handleLinkPreviewMessage(c *gin.Context, groupClient client.GroupClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body LinkPreviewPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !checkGroupMember(c, groupClient, username, body.GroupID) {
+		return
+	}
+
+	// The sender got the message without a preview as well
+	relayToGroup("", body.GroupID, MessageLinkPreview, body)
+}
+
+func handleExpireMessage(c *gin.Context) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	var body ExpirePayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Expiry is not caused by any member, everyone in the group is told
+	relayToGroup("", body.GroupID, MessageExpire, body)
 }
 
 func handleNotificationMessage(c *gin.Context) {
@@ -91,8 +261,10 @@ func handleNotificationMessage(c *gin.Context) {
 		return
 	}
 
+	hubLock.RLock()
 	fmt.Printf("[NOTI] %v: %v\n", len(clients), clients)
 	client, ok := clients[body.Username]
+	hubLock.RUnlock()
 	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not connected"})
 		return
@@ -107,13 +279,13 @@ func handleNotificationMessage(c *gin.Context) {
 		Type:    MessageNotification,
 		Payload: json.RawMessage(payload),
 	}
-	if client.Conn.WriteJSON(msg) != nil {
+	if client.WriteJSON(msg) != nil {
 		fmt.Println("Failed to send notification message through socket:", err)
 		return
 	}
 }
 
-func handleWebsocket(c *gin.Context, groupClient client.GroupClient) {
+func handleWebsocket(c *gin.Context, groupClient client.GroupClient, userClient client.UserClient, messageClient client.MessageClient) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		fmt.Println("WebSocket upgrade failed:", err)
@@ -161,24 +333,33 @@ func handleWebsocket(c *gin.Context, groupClient client.GroupClient) {
 		return
 	}
 
+	friends, err := userClient.GetFriendUsernames(username)
+	if err != nil {
+		fmt.Println("Failed to get friends:", err)
+		conn.Close()
+		return
+	}
+
 	gids := make([]int, len(groups))
 	for i, g := range groups {
 		gids[i] = g.ID
 	}
 
 	client := &Client{
-		Username: username,
-		Conn:     conn,
-		Groups:   gids,
+		Username:   username,
+		Conn:       conn,
+		Groups:     gids,
+		Friends:    friends,
+		lastTyping: make(map[int]time.Time),
 	}
 
-	for _, group := range groups {
-		if groupMembers[group.ID] == nil {
-			groupMembers[group.ID] = make([]string, 1)
-		}
-		groupMembers[group.ID] = append(groupMembers[group.ID], username)
+	hubLock.Lock()
+	for _, groupID := range gids {
+		addGroupMember(groupID, username)
 	}
 	clients[username] = client
+	delete(lastSeen, username)
+	hubLock.Unlock()
 
 	fmt.Printf("[CONNECTED] %s in groups %v\n", username, groups)
 
@@ -187,31 +368,39 @@ func handleWebsocket(c *gin.Context, groupClient client.GroupClient) {
 		Payload: json.RawMessage(`{"status":"success"}`),
 	}
 	authSuccessBytes, _ := json.Marshal(authSuccessMsg)
-	conn.WriteMessage(websocket.TextMessage, authSuccessBytes)
+	client.WriteMessage(authSuccessBytes)
+
+	broadcastPresence(client, PresenceOnline, nil)
 
-	go handleMessages(client)
+	go handleMessages(client, groupClient, messageClient)
 }
 
-func handleMessages(client *Client) {
+func handleMessages(client *Client, groupClient client.GroupClient, messageClient client.MessageClient) {
 	defer func() {
 		fmt.Printf("[DISCONNECTED] %s\n", client.Username)
 
+		for groupID := range client.lastTyping {
+			stopTyping(client, groupID)
+		}
+
+		hubLock.Lock()
 		for _, groupID := range client.Groups {
-			if members, exists := groupMembers[groupID]; exists {
-				for i, member := range members {
-					if member == client.Username {
-						groupMembers[groupID] = append(members[:i], members[i+1:]...)
-						break
-					}
-				}
-				if len(members) == 0 {
-					delete(groupMembers, groupID)
-				}
-			}
+			removeGroupMember(groupID, client.Username)
 		}
 
-		delete(clients, client.Username)
+		// a newer connection of the same user may have replaced this one
+		disconnectedAt := time.Now()
+		isCurrent := clients[client.Username] == client
+		if isCurrent {
+			delete(clients, client.Username)
+			lastSeen[client.Username] = disconnectedAt
+		}
+		hubLock.Unlock()
 		client.Conn.Close()
+
+		if isCurrent {
+			broadcastPresence(client, PresenceOffline, &disconnectedAt)
+		}
 	}()
 
 	for {
@@ -237,7 +426,7 @@ func handleMessages(client *Client) {
 				fmt.Println("Invalid chat payload:", err)
 				continue
 			}
-			broadcastToGroup(chat.GroupID, msgBytes, chat.Username)
+			handleSocketChat(client, chat, groupClient, messageClient)
 
 		case MessageNotification:
 			var notif NotificationPayload
@@ -247,7 +436,15 @@ func handleMessages(client *Client) {
 			}
 
 			fmt.Printf("[NOTIFICATION] To %s: %s - %s\n", client.Username, notif.Desc, notif.Link)
-			client.Conn.WriteMessage(websocket.TextMessage, msgBytes)
+			client.WriteMessage(msgBytes)
+
+		case MessageTyping:
+			var typing TypingPayload
+			if err := json.Unmarshal(msg.Payload, &typing); err != nil {
+				fmt.Println("Invalid typing payload:", err)
+				continue
+			}
+			handleTyping(client, typing)
 
 		default:
 			fmt.Println("Unknown message type:\n", msg)
@@ -263,20 +460,96 @@ func updateGroups(groupClient client.GroupClient, authUsername string, username
 	return gu, nil
 }
 
-func broadcastToGroup(groupID int, message []byte, ignore string) {
-	if users, ok := groupMembers[groupID]; ok {
-		for _, username := range users {
-			if username == ignore {
-				continue
-			}
-			if client, ok := clients[username]; ok {
-				client.Conn.WriteMessage(websocket.TextMessage, message)
-			}
+// checkGroupMember asks the message service rather than the hub, which only knows the groups of
+// connected users, and answers the request itself when username is not a member
+func checkGroupMember(c *gin.Context, groupClient client.GroupClient, username string, groupID int) bool {
+	groups, err := updateGroups(groupClient, username, username)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to check group membership"})
+		return false
+	}
+	for _, g := range groups {
+		if g.ID == groupID {
+			return true
 		}
 	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "User not in group"})
+	return false
+}
+
+// syncGroups refetches the groups of a connected client so groups joined after connecting are picked up
+func syncGroups(c *Client, groupClient client.GroupClient) error {
+	groups, err := updateGroups(groupClient, c.Username, c.Username)
+	if err != nil {
+		return err
+	}
+
+	hubLock.Lock()
+	defer hubLock.Unlock()
+	for _, groupID := range c.Groups {
+		removeGroupMember(groupID, c.Username)
+	}
+	c.Groups = make([]int, len(groups))
+	for i, g := range groups {
+		c.Groups[i] = g.ID
+		addGroupMember(g.ID, c.Username)
+	}
+	return nil
+}
+
+// addGroupMember expects hubLock to be held by the caller
+func addGroupMember(groupID int, username string) {
+	for _, member := range groupMembers[groupID] {
+		if member == username {
+			return
+		}
+	}
+	groupMembers[groupID] = append(groupMembers[groupID], username)
+}
+
+// removeGroupMember expects hubLock to be held by the caller
+func removeGroupMember(groupID int, username string) {
+	members, exists := groupMembers[groupID]
+	if !exists {
+		return
+	}
+	for i, member := range members {
+		if member == username {
+			members = append(members[:i], members[i+1:]...)
+			break
+		}
+	}
+	if len(members) == 0 {
+		delete(groupMembers, groupID)
+	} else {
+		groupMembers[groupID] = members
+	}
+}
+
+// relayToGroup wraps an event coming from an internal service and broadcasts it to the connected group members
+func relayToGroup(ignore string, groupID int, msgType MessageType, body interface{}) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		fmt.Printf("Failed to marshal %s payload: %v\n", msgType, err)
+		return
+	}
+	msg := Message{
+		Type:    msgType,
+		Payload: json.RawMessage(payload),
+	}
+	tryBroadcastToGroup(ignore, groupID, msg)
 }
 
 func tryBroadcastToGroup(ignore string, groupID int, message interface{}) bool {
+	return tryBroadcastToGroupWith(ignore, groupID, func(string) interface{} {
+		return message
+	})
+}
+
+// tryBroadcastToGroupWith lets the message vary per recipient
+func tryBroadcastToGroupWith(ignore string, groupID int, message func(recipient string) interface{}) bool {
+	hubLock.RLock()
+	defer hubLock.RUnlock()
 	users, ok := groupMembers[groupID]
 	if ok {
 		for _, username := range users {
@@ -284,7 +557,7 @@ func tryBroadcastToGroup(ignore string, groupID int, message interface{}) bool {
 				continue
 			}
 			if client, ok := clients[username]; ok {
-				client.Conn.WriteJSON(message)
+				client.WriteJSON(message(username))
 			}
 		}
 	}
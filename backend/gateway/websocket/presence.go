@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gateway/client"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lastSeen holds the disconnect time of users that are currently offline
+var lastSeen = make(map[string]time.Time)
+
+// handlePresence only answers for the caller's friends, the other names are left out
+func handlePresence(c *gin.Context, userClient client.UserClient) {
+	username := c.Request.Header.Get("X-Username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No username provided"})
+		return
+	}
+
+	query := c.Query("usernames")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No usernames provided"})
+		return
+	}
+
+	friends, err := userClient.GetFriendUsernames(username)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get friends"})
+		return
+	}
+	isFriend := make(map[string]bool, len(friends))
+	for _, f := range friends {
+		isFriend[f] = true
+	}
+
+	usernames := strings.Split(query, ",")
+	presences := make([]PresencePayload, 0, len(usernames))
+
+	hubLock.RLock()
+	for _, u := range usernames {
+		u = strings.TrimSpace(u)
+		if u == "" || !isFriend[u] {
+			continue
+		}
+		presences = append(presences, getPresence(u))
+	}
+	hubLock.RUnlock()
+
+	c.JSON(http.StatusOK, presences)
+}
+
+// getPresence expects hubLock to be held by the caller
+func getPresence(username string) PresencePayload {
+	if _, ok := clients[username]; ok {
+		return PresencePayload{Username: username, Status: PresenceOnline}
+	}
+	presence := PresencePayload{Username: username, Status: PresenceOffline}
+	if t, ok := lastSeen[username]; ok {
+		presence.LastSeen = &t
+	}
+	return presence
+}
+
+func broadcastPresence(client *Client, status PresenceStatus, at *time.Time) {
+	payload, err := json.Marshal(PresencePayload{
+		Username: client.Username,
+		Status:   status,
+		LastSeen: at,
+	})
+	if err != nil {
+		fmt.Println("Failed to marshal presence payload:", err)
+		return
+	}
+	msg := Message{
+		Type:    MessagePresence,
+		Payload: json.RawMessage(payload),
+	}
+
+	hubLock.RLock()
+	defer hubLock.RUnlock()
+	for _, friend := range client.Friends {
+		if friendClient, ok := clients[friend]; ok {
+			friendClient.WriteJSON(msg)
+		}
+	}
+}
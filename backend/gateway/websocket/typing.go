@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// typingInterval is the minimum gap between two "typing started" frames relayed for the same group
+const typingInterval = 3 * time.Second
+
+func handleTyping(client *Client, typing TypingPayload) {
+	if !client.InGroup(typing.GroupID) {
+		fmt.Printf("[TYPING] %s is not a member of group %d\n", client.Username, typing.GroupID)
+		return
+	}
+
+	if !typing.Typing {
+		if _, ok := client.lastTyping[typing.GroupID]; ok {
+			stopTyping(client, typing.GroupID)
+		}
+		return
+	}
+
+	if last, ok := client.lastTyping[typing.GroupID]; ok && time.Since(last) < typingInterval {
+		return
+	}
+	client.lastTyping[typing.GroupID] = time.Now()
+	sendTyping(client.Username, typing.GroupID, true)
+}
+
+func stopTyping(client *Client, groupID int) {
+	delete(client.lastTyping, groupID)
+	sendTyping(client.Username, groupID, false)
+}
+
+func sendTyping(username string, groupID int, typing bool) {
+	payload, err := json.Marshal(TypingPayload{
+		Username: username,
+		GroupID:  groupID,
+		Typing:   typing,
+	})
+	if err != nil {
+		fmt.Println("Failed to marshal typing payload:", err)
+		return
+	}
+	msg := Message{
+		Type:    MessageTyping,
+		Payload: json.RawMessage(payload),
+	}
+	tryBroadcastToGroup(username, groupID, msg)
+}
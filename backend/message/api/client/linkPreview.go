@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"message/entity"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Only the head of a page is needed for its metadata
+const maxPreviewBodySize = 512 * 1024
+
+var (
+	titleRegex     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaRegex      = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributeRegex = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+type LinkPreviewClient interface {
+	FetchPreview(link string) (*entity.LinkPreview, error)
+}
+
+type LinkPreviewClientImpl struct {
+	httpClient *http.Client
+}
+
+func NewLinkPreviewClient() LinkPreviewClient {
+	dialer := &net.Dialer{
+		Timeout: 3 * time.Second,
+		Control: rejectPrivateAddress,
+	}
+	return &LinkPreviewClientImpl{
+		httpClient: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+	}
+}
+
+func (c *LinkPreviewClientImpl) FetchPreview(link string) (*entity.LinkPreview, error) {
+	base, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch link preview: %s", resp.Status)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+		return nil, errors.New("link is not an html page")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parsePreview(string(body))
+	preview.URL = link
+	if preview.Image != "" {
+		if imageURL, err := base.Parse(preview.Image); err == nil {
+			preview.Image = imageURL.String()
+		}
+	}
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return nil, errors.New("link has no preview metadata")
+	}
+
+	return preview, nil
+}
+
+func parsePreview(page string) *entity.LinkPreview {
+	preview := &entity.LinkPreview{}
+	for _, tag := range metaRegex.FindAllString(page, -1) {
+		var key, content string
+		for _, attr := range attributeRegex.FindAllStringSubmatch(tag, -1) {
+			value := html.UnescapeString(strings.Trim(attr[2], `"'`))
+			switch strings.ToLower(attr[1]) {
+			case "property", "name":
+				key = strings.ToLower(value)
+			case "content":
+				content = strings.TrimSpace(value)
+			}
+		}
+
+		switch key {
+		case "og:title":
+			preview.Title = content
+		case "og:description":
+			preview.Description = content
+		case "description":
+			if preview.Description == "" {
+				preview.Description = content
+			}
+		case "og:image":
+			preview.Image = content
+		}
+	}
+
+	if preview.Title == "" {
+		if match := titleRegex.FindStringSubmatch(page); match != nil {
+			preview.Title = strings.TrimSpace(html.UnescapeString(match[1]))
+		}
+	}
+	if len(preview.Description) > 1024 {
+		preview.Description = preview.Description[:1024]
+	}
+
+	return preview
+}
+
+// rejectPrivateAddress keeps user supplied links from reaching services inside the cluster
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("link preview to %s is not allowed", host)
+	}
+	return nil
+}
+
+// StubLinkPreviewClient builds previews without network access, for local development
+type StubLinkPreviewClient struct{}
+
+func NewStubLinkPreviewClient() LinkPreviewClient {
+	return &StubLinkPreviewClient{}
+}
+
+func (c *StubLinkPreviewClient) FetchPreview(link string) (*entity.LinkPreview, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.LinkPreview{
+		URL:         link,
+		Title:       u.Host,
+		Description: link,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"message/api/presenter"
+	"net/http"
+)
+
+type NotiClient interface {
+	// CreateNoti returns a nil notification when the receiver has a block relation with the sender
+	CreateNoti(sender, username, icon, desc, link string) (*presenter.Notification, error)
+	CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) error
+}
+
+type NotiClientImpl struct {
+	notiUrl string
+}
+
+func NewNotiClient(notiUrl string) NotiClient {
+	return &NotiClientImpl{
+		notiUrl: notiUrl,
+	}
+}
+
+func (c *NotiClientImpl) CreateNoti(sender, username, icon, desc, link string) (*presenter.Notification, error) {
+	body, err := json.Marshal(&presenter.NotiCreateRequest{
+		Sender:   sender,
+		Username: username,
+		Icon:     icon,
+		Desc:     desc,
+		Link:     link,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.notiUrl+"/api/noti", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Username", "system")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	var noti presenter.Notification
+	if err := json.NewDecoder(resp.Body).Decode(&noti); err != nil {
+		return nil, err
+	}
+
+	return &noti, nil
+}
+
+func (c *NotiClientImpl) CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) error {
+	body, err := json.Marshal(&presenter.NotiToUsersCreateRequest{
+		Sender:    sender,
+		Usernames: usernames,
+		Icon:      icon,
+		Desc:      desc,
+		Link:      link,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.notiUrl+"/api/noti/createMultiple", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Username", "system")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
@@ -3,12 +3,14 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"message/api/presenter"
 	"net/http"
 )
 
 type UserClient interface {
 	FindUsers([]string) ([]*presenter.User, error)
+	GetBlockRelations(username string) ([]string, error)
 }
 
 type UserClientImpl struct {
@@ -44,3 +46,46 @@ func (c *UserClientImpl) FindUsers(usernames []string) ([]*presenter.User, error
 
 	return users, nil
 }
+
+// GetBlockRelations lists the users who either blocked or were blocked by the given user.
+// It fails closed: any failure to get the list is an error, so callers refuse the request
+// instead of treating the user as having no blocks
+func (c *UserClientImpl) GetBlockRelations(username string) ([]string, error) {
+	req, err := http.NewRequest("GET", c.userUrl+"/internal/user/blocks/related", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Username", username)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get block relations: %s", resp.Status)
+	}
+
+	var usernames []string
+	if err := json.NewDecoder(resp.Body).Decode(&usernames); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
+
+// IsBlocked reports whether either of the two users blocked the other
+func IsBlocked(userClient UserClient, userA string, userB string) (bool, error) {
+	related, err := userClient.GetBlockRelations(userA)
+	if err != nil {
+		return false, err
+	}
+	for _, username := range related {
+		if username == userB {
+			return true, nil
+		}
+	}
+	return false, nil
+}
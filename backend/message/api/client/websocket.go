@@ -3,12 +3,21 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"message/api/presenter"
+	"message/e2ee"
 	"net/http"
 	"time"
 )
 
 type WsClient interface {
-	SendMessage(int, string, int, string, time.Time) error
+	SendMessage(string, *presenter.Message, []string) error
+	SendRead(string, int, int) error
+	SendEdit(string, int, int, string, time.Time) error
+	SendDelete(string, int, int) error
+	SendReaction(string, int, int, string) error
+	SendLinkPreview(string, int, int, *presenter.LinkPreview) error
+	SendExpired(int, []int) error
 }
 
 type WsClientImpl struct {
@@ -16,10 +25,56 @@ type WsClientImpl struct {
 }
 
 type WsMessageRequest struct {
+	MessageID   int                     `json:"messageId"`
+	GroupID     int                     `json:"groupId"`
+	Content     string                  `json:"content"`
+	CreatedAt   time.Time               `json:"createdAt"`
+	System      bool                    `json:"system,omitempty"`
+	Protocol    string                  `json:"protocol"`
+	Encrypted   *e2ee.EncryptedMessage  `json:"encrypted,omitempty"`
+	Attachments []*presenter.Attachment `json:"attachments"`
+	LinkPreview *presenter.LinkPreview  `json:"linkPreview"`
+	ReplyTo     *presenter.MessageReply `json:"replyTo,omitempty"`
+
+	// Members who muted the group get the message without an alert
+	MutedUsernames []string `json:"mutedUsernames,omitempty"`
+}
+
+type WsReadRequest struct {
+	GroupID   int `json:"groupId"`
+	MessageID int `json:"messageId"`
+}
+
+type WsEditRequest struct {
 	MessageID int       `json:"messageId"`
 	GroupID   int       `json:"groupId"`
 	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+type WsDeleteRequest struct {
+	MessageID int `json:"messageId"`
+	GroupID   int `json:"groupId"`
+}
+
+// An empty Type tells clients the reaction was removed
+type WsReactionRequest struct {
+	MessageID int    `json:"messageId"`
+	GroupID   int    `json:"groupId"`
+	Type      string `json:"type"`
+}
+
+// WsLinkPreviewRequest carries a preview fetched after its message was sent
+type WsLinkPreviewRequest struct {
+	MessageID   int                    `json:"messageId"`
+	GroupID     int                    `json:"groupId"`
+	LinkPreview *presenter.LinkPreview `json:"linkPreview"`
+}
+
+// WsExpireRequest lists messages removed by the group's retention policy
+type WsExpireRequest struct {
+	GroupID    int   `json:"groupId"`
+	MessageIDs []int `json:"messageIds"`
 }
 
 func NewWsClient(wsUrl string) WsClient {
@@ -28,18 +83,107 @@ func NewWsClient(wsUrl string) WsClient {
 	}
 }
 
-func (c *WsClientImpl) SendMessage(messageId int, username string, groupId int, content string, createdAt time.Time) error {
+func (c *WsClientImpl) SendMessage(username string, msg *presenter.Message, mutedUsernames []string) error {
 	body, err := json.Marshal(&WsMessageRequest{
+		MessageID:   msg.ID,
+		GroupID:     msg.GroupID,
+		Content:     msg.Content,
+		CreatedAt:   msg.CreatedAt,
+		System:      msg.System,
+		Protocol:    string(msg.Protocol),
+		Encrypted:   msg.Encrypted,
+		Attachments: msg.Attachments,
+		LinkPreview: msg.LinkPreview,
+		ReplyTo:     msg.ReplyTo,
+
+		MutedUsernames: mutedUsernames,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post(username, "/ws/message", body)
+}
+
+func (c *WsClientImpl) SendRead(username string, groupId int, messageId int) error {
+	body, err := json.Marshal(&WsReadRequest{
+		GroupID:   groupId,
+		MessageID: messageId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post(username, "/ws/read", body)
+}
+
+func (c *WsClientImpl) SendEdit(username string, messageId int, groupId int, content string, editedAt time.Time) error {
+	body, err := json.Marshal(&WsEditRequest{
 		MessageID: messageId,
 		GroupID:   groupId,
 		Content:   content,
-		CreatedAt: createdAt,
+		EditedAt:  editedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post(username, "/ws/edit", body)
+}
+
+func (c *WsClientImpl) SendDelete(username string, messageId int, groupId int) error {
+	body, err := json.Marshal(&WsDeleteRequest{
+		MessageID: messageId,
+		GroupID:   groupId,
 	})
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", c.wsUrl+"/ws/message", bytes.NewBuffer(body))
+	return c.post(username, "/ws/delete", body)
+}
+
+func (c *WsClientImpl) SendReaction(username string, messageId int, groupId int, reactionType string) error {
+	body, err := json.Marshal(&WsReactionRequest{
+		MessageID: messageId,
+		GroupID:   groupId,
+		Type:      reactionType,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post(username, "/ws/reaction", body)
+}
+
+func (c *WsClientImpl) SendLinkPreview(username string, messageId int, groupId int, preview *presenter.LinkPreview) error {
+	body, err := json.Marshal(&WsLinkPreviewRequest{
+		MessageID:   messageId,
+		GroupID:     groupId,
+		LinkPreview: preview,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post(username, "/ws/link-preview", body)
+}
+
+func (c *WsClientImpl) SendExpired(groupId int, messageIds []int) error {
+	body, err := json.Marshal(&WsExpireRequest{
+		GroupID:    groupId,
+		MessageIDs: messageIds,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.post("system", "/ws/expire", body)
+}
+
+// post fails on any non-2xx status so callers can tell when an event never reached the sockets
+func (c *WsClientImpl) post(username string, path string, body []byte) error {
+	req, err := http.NewRequest("POST", c.wsUrl+path, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
@@ -47,10 +191,15 @@ func (c *WsClientImpl) SendMessage(messageId int, username string, groupId int,
 	req.Header.Set("X-Username", username)
 
 	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post %s: %s", path, resp.Status)
+	}
 
 	return nil
 }
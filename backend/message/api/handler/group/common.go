@@ -9,65 +9,152 @@ import (
 )
 
 func groupEntityToPresenter(in *entity.Group, username string, groupService group.UseCase, messageService message.UseCase, userService client.UserClient) (*presenter.Group, error) {
-	users, err := groupService.GetMembers(in.ID)
+	out, err := groupListEntityToPresenter([]*entity.Group{in}, username, groupService, messageService, userService)
 	if err != nil {
 		return nil, err
 	}
-	lastMessageEntity, err := messageService.GetLastMessage(in.ID)
+	return out[0], nil
+}
+
+// groupListEntityToPresenter loads members, latest messages, unread counts and direct chat peers
+// for all groups in a fixed number of queries
+func groupListEntityToPresenter(in []*entity.Group, username string, groupService group.UseCase, messageService message.UseCase, userService client.UserClient) (out []*presenter.Group, err error) {
+	out = make([]*presenter.Group, 0, len(in))
+	if len(in) == 0 {
+		return out, nil
+	}
+
+	groupIDs := make([]int, len(in))
+	for i, g := range in {
+		groupIDs[i] = g.ID
+	}
+
+	members, err := groupService.GetMembersOfGroups(groupIDs)
 	if err != nil {
 		return nil, err
 	}
-	var lastMessage *presenter.Message
-	if lastMessageEntity != nil {
-		lastMessage = &presenter.Message{
-			ID:        lastMessageEntity.ID,
-			Content:   lastMessageEntity.Content,
-			Username:  lastMessageEntity.Username,
-			GroupID:   lastMessageEntity.GroupID,
-			CreatedAt: lastMessageEntity.CreatedAt,
-		}
+	lastMessages, err := messageService.GetLastMessages(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	unreadCounts, err := messageService.CountUnreadMessagesOfGroups(username, groupIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	var oppUsername string
-	for _, u := range users {
-		if u != username {
-			oppUsername = u
-			break
+	oppUsernames := make(map[int]string)
+	var peers []string
+	for _, g := range in {
+		if !g.IsDirect {
+			continue
+		}
+		for _, gu := range members[g.ID] {
+			if gu.Username != username {
+				oppUsernames[g.ID] = gu.Username
+				peers = append(peers, gu.Username)
+				break
+			}
 		}
 	}
-	if in.IsDirect {
-		directUser, err := userService.FindUsers([]string{oppUsername})
+	directUsers := make(map[string]*presenter.User)
+	if len(peers) > 0 {
+		users, err := userService.FindUsers(peers)
 		if err != nil {
 			return nil, err
 		}
-		return &presenter.Group{
-			ID:          in.ID,
-			Name:        directUser[0].DisplayName,
-			Avatar:      directUser[0].Avatar,
-			IsDirect:    in.IsDirect,
-			Members:     users,
-			LastMessage: lastMessage,
-		}, nil
-	} else {
-		return &presenter.Group{
-			ID:          in.ID,
-			Name:        in.Name,
-			IsDirect:    in.IsDirect,
-			OwnerName:   in.OwnerName,
-			Members:     users,
-			LastMessage: lastMessage,
-		}, nil
+		for _, u := range users {
+			directUsers[u.Username] = u
+		}
 	}
-}
 
-func groupListEntityToPresenter(in []*entity.Group, username string, groupService group.UseCase, messageService message.UseCase, userService client.UserClient) (out []*presenter.Group, err error) {
-	out = make([]*presenter.Group, 0)
 	for _, g := range in {
-		pg, err := groupEntityToPresenter(g, username, groupService, messageService, userService)
-		if err != nil {
-			return nil, err
+		pg := &presenter.Group{
+			ID:          g.ID,
+			IsDirect:    g.IsDirect,
+			Members:     make([]string, 0, len(members[g.ID])),
+			UnreadCount: unreadCounts[g.ID],
+			LastReads:   make(map[string]int, len(members[g.ID])),
+			CreatedAt:   g.CreatedAt,
+
+			RetentionPolicy: g.RetentionPolicy,
+			RetentionDays:   g.RetentionDays,
+		}
+		if lastMessage, ok := lastMessages[g.ID]; ok {
+			pg.LastMessage = presenter.MessageEntityToPresenter(lastMessage)
+		}
+
+		roles := make(map[string]entity.GroupRole, len(members[g.ID]))
+		for _, gu := range members[g.ID] {
+			pg.Members = append(pg.Members, gu.Username)
+			pg.LastReads[gu.Username] = gu.LastReadMessageID
+			roles[gu.Username] = gu.Role
+			if gu.Username == username {
+				pg.Preferences = preferencesEntityToPresenter(gu)
+			}
+		}
+
+		if g.IsDirect {
+			pg.Name = oppUsernames[g.ID]
+			pg.Encrypted = g.Encrypted
+			if u, ok := directUsers[oppUsernames[g.ID]]; ok {
+				pg.Name = u.DisplayName
+				pg.Avatar = u.Avatar
+			}
+		} else {
+			pg.Name = g.Name
+			pg.Avatar = g.Avatar
+			pg.OwnerName = g.OwnerName
+			pg.Roles = roles
+			pg.RequiresApproval = g.RequiresApproval
+			pg.OnlyAdminsPost = g.OnlyAdminsPost
+			pg.OnlyAdminsEditInfo = g.OnlyAdminsEditInfo
+			pg.Description = g.Description
 		}
+
 		out = append(out, pg)
 	}
 	return out, nil
 }
+
+func inviteEntityToPresenter(in *entity.GroupInvite) *presenter.GroupInvite {
+	return &presenter.GroupInvite{
+		Code:      in.Code,
+		GroupID:   in.GroupID,
+		CreatedBy: in.CreatedBy,
+		ExpiresAt: in.ExpiresAt,
+		MaxUses:   in.MaxUses,
+		Uses:      in.Uses,
+		CreatedAt: in.CreatedAt,
+	}
+}
+
+func inviteListEntityToPresenter(in []*entity.GroupInvite) (out []*presenter.GroupInvite) {
+	out = make([]*presenter.GroupInvite, 0)
+	for _, invite := range in {
+		out = append(out, inviteEntityToPresenter(invite))
+	}
+	return out
+}
+
+func joinRequestListEntityToPresenter(in []*entity.JoinRequest) (out []*presenter.JoinRequest) {
+	out = make([]*presenter.JoinRequest, 0)
+	for _, request := range in {
+		out = append(out, &presenter.JoinRequest{
+			Username:  request.Username,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+	return out
+}
+
+func preferencesEntityToPresenter(in *entity.GroupUser) *presenter.GroupPreferences {
+	out := &presenter.GroupPreferences{
+		Muted:    in.IsMuted(),
+		Archived: in.Archived,
+		Pinned:   in.Pinned,
+	}
+	if out.Muted {
+		out.MutedUntil = in.MutedUntil
+	}
+	return out
+}
@@ -9,11 +9,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func MakeHandler(app *gin.Engine, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+func MakeHandler(app *gin.Engine, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient, wsClient client.WsClient) {
 	groupGroup := app.Group("/api/group")
 	{
 		authGroup := groupGroup.Group("", middleware.MustAuthMiddleware())
 
+		authGroup.GET("/ids", func(ctx *gin.Context) {
+			getGroupIDsOfUser(ctx, groupService)
+		})
+
 		authGroup.GET("/:groupId", func(ctx *gin.Context) {
 			getGroup(ctx, groupService, messageService, userClient)
 		})
@@ -38,8 +42,8 @@ func MakeHandler(app *gin.Engine, groupService group.UseCase, messageService mes
 			deleteGroup(ctx, groupService)
 		})
 
-		authGroup.PUT("", func(ctx *gin.Context) {
-			updateGroup(ctx, groupService, messageService, userClient)
+		authGroup.PUT("/:groupId", func(ctx *gin.Context) {
+			updateGroup(ctx, groupService, messageService, userClient, wsClient)
 		})
 
 		authGroup.POST("/:groupId/members", func(ctx *gin.Context) {
@@ -49,5 +53,69 @@ func MakeHandler(app *gin.Engine, groupService group.UseCase, messageService mes
 		authGroup.DELETE("/:groupId/members", func(ctx *gin.Context) {
 			removeMemberToGroup(ctx, groupService, messageService, userClient)
 		})
+
+		authGroup.PUT("/:groupId/members/role", func(ctx *gin.Context) {
+			setMemberRole(ctx, groupService, messageService, userClient)
+		})
+
+		authGroup.POST("/:groupId/owner", func(ctx *gin.Context) {
+			transferOwnership(ctx, groupService, messageService, userClient)
+		})
+
+		authGroup.POST("/:groupId/mute", func(ctx *gin.Context) {
+			muteGroup(ctx, groupService)
+		})
+
+		authGroup.DELETE("/:groupId/mute", func(ctx *gin.Context) {
+			unmuteGroup(ctx, groupService)
+		})
+
+		authGroup.PUT("/:groupId/archive", func(ctx *gin.Context) {
+			archiveGroup(ctx, groupService)
+		})
+
+		authGroup.PUT("/:groupId/pin", func(ctx *gin.Context) {
+			pinGroup(ctx, groupService)
+		})
+
+		authGroup.PUT("/:groupId/approval", func(ctx *gin.Context) {
+			setJoinApproval(ctx, groupService, messageService, userClient)
+		})
+
+		authGroup.PUT("/:groupId/retention", func(ctx *gin.Context) {
+			setRetention(ctx, groupService, messageService, userClient, wsClient)
+		})
+
+		authGroup.PUT("/:groupId/encryption", func(ctx *gin.Context) {
+			setEncryption(ctx, groupService, messageService, userClient, wsClient)
+		})
+
+		authGroup.POST("/:groupId/invites", func(ctx *gin.Context) {
+			createInvite(ctx, groupService)
+		})
+
+		authGroup.GET("/:groupId/invites", func(ctx *gin.Context) {
+			getInvites(ctx, groupService)
+		})
+
+		authGroup.DELETE("/:groupId/invites/:code", func(ctx *gin.Context) {
+			revokeInvite(ctx, groupService)
+		})
+
+		authGroup.POST("/join/:code", func(ctx *gin.Context) {
+			joinByInvite(ctx, groupService, messageService, userClient)
+		})
+
+		authGroup.GET("/:groupId/requests", func(ctx *gin.Context) {
+			getJoinRequests(ctx, groupService)
+		})
+
+		authGroup.POST("/:groupId/requests/:username", func(ctx *gin.Context) {
+			approveJoinRequest(ctx, groupService, messageService, userClient)
+		})
+
+		authGroup.DELETE("/:groupId/requests/:username", func(ctx *gin.Context) {
+			declineJoinRequest(ctx, groupService)
+		})
 	}
 }
@@ -1,17 +1,44 @@
 package group
 
 import (
+	"errors"
+	"fmt"
 	"message/api/client"
 	payload "message/api/payload/group"
+	"message/api/presenter"
+	"message/entity"
 	"message/usecase/group"
 	"message/usecase/message"
 	"message/util"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, group.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, group.ErrEmptyName), errors.Is(err, group.ErrDescriptionTooLong), errors.Is(err, group.ErrInvalidRetention):
+		return http.StatusBadRequest
+	case errors.Is(err, group.ErrNotMember), errors.Is(err, group.ErrInvalidRole), errors.Is(err, group.ErrDirectGroup), errors.Is(err, group.ErrNotDirectGroup), errors.Is(err, group.ErrInvalidInvite):
+		return http.StatusBadRequest
+	case errors.Is(err, group.ErrInviteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, group.ErrInviteExpired), errors.Is(err, group.ErrInviteExhausted):
+		return http.StatusGone
+	case errors.Is(err, group.ErrAlreadyMember):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func getGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
 	groupIdParam := ctx.Param("groupId")
 	groupId, err := strconv.Atoi(groupIdParam)
@@ -58,7 +85,7 @@ func createGroup(ctx *gin.Context, groupService group.UseCase, messageService me
 	ctx.JSON(http.StatusCreated, groupPresenter)
 }
 
-func updateGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+func updateGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient, wsClient client.WsClient) {
 	groupIdParam := ctx.Param("groupId")
 	groupId, err := strconv.Atoi(groupIdParam)
 	if err != nil {
@@ -73,23 +100,38 @@ func updateGroup(ctx *gin.Context, groupService group.UseCase, messageService me
 		return
 	}
 
-	g, err := groupService.GetGroup(groupId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
-		return
-	}
-
 	username := util.MustGetUsername(ctx)
-	if username != g.OwnerName {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to update group"})
+	g, changes, err := groupService.UpdateGroup(username, groupId, group.GroupUpdate{
+		Name:               body.GroupName,
+		Avatar:             body.Avatar,
+		Description:        body.Description,
+		OnlyAdminsPost:     body.OnlyAdminsPost,
+		OnlyAdminsEditInfo: body.OnlyAdminsEditInfo,
+	})
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	g, err = groupService.UpdateGroup(groupId, body.GroupName)
+	muted, err := groupService.GetMutedMembers(g.ID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for _, change := range changes {
+		content := "group:" + change
+		if change == "name" {
+			content += ":" + g.Name
+		}
+		msg, err := messageService.SendSystemMessage(username, g.ID, content)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := wsClient.SendMessage(username, presenter.MessageEntityToPresenter(msg), muted); err != nil {
+			fmt.Println("Failed to send system message:", err)
+		}
+	}
 
 	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
@@ -108,38 +150,53 @@ func deleteGroup(ctx *gin.Context, groupService group.UseCase) {
 		return
 	}
 
-	g, err := groupService.GetGroup(body.GroupID)
+	err = groupService.DeleteGroup(util.MustGetUsername(ctx), body.GroupID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.Status(http.StatusNoContent)
+}
+
+func getDirectGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
 	username := util.MustGetUsername(ctx)
-	if username != g.OwnerName {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to delete group"})
+	oppUsername := ctx.Param("username")
+
+	g, err := groupService.GetDirectGroup(username, oppUsername)
+	if errors.Is(err, group.ErrBlocked) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
 		return
 	}
 
-	err = groupService.DeleteGroup(body.GroupID)
+	groupPresenter, err := groupEntityToPresenter(g, username, groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.Status(http.StatusNoContent)
+	ctx.JSON(http.StatusOK, groupPresenter)
 }
 
-func getDirectGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
-	username := util.MustGetUsername(ctx)
-	oppUsername := ctx.Param("username")
+func addMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+	var body payload.GroupMemberPayload
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse group member payload"})
+		return
+	}
 
-	g, err := groupService.GetDirectGroup(username, oppUsername)
+	g, err := groupService.AddMember(util.MustGetUsername(ctx), body.GroupID, body.Username)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	groupPresenter, err := groupEntityToPresenter(g, username, groupService, messageService, userClient)
+	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -148,7 +205,7 @@ func getDirectGroup(ctx *gin.Context, groupService group.UseCase, messageService
 	ctx.JSON(http.StatusOK, groupPresenter)
 }
 
-func addMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+func removeMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
 	var body payload.GroupMemberPayload
 	err := ctx.ShouldBindJSON(&body)
 	if err != nil {
@@ -156,24 +213,84 @@ func addMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageServi
 		return
 	}
 
-	g, err := groupService.GetGroup(body.GroupID)
+	g, err := groupService.RemoveMember(util.MustGetUsername(ctx), body.GroupID, body.Username)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.JSON(http.StatusOK, groupPresenter)
+}
+
+func getGroupsOfUser(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
 	username := util.MustGetUsername(ctx)
-	if username != g.OwnerName {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to add members"})
+	pagination := util.ExtractPagination(ctx)
+
+	archived := ctx.Query("archived") == "true"
+
+	groups, err := groupService.GetGroupsOfUser(username, archived, pagination)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	g, err = groupService.AddMember(body.GroupID, body.Username)
+	groupPresenters, err := groupListEntityToPresenter(groups, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.JSON(http.StatusOK, groupPresenters)
+}
+
+func getMembersOfGroup(ctx *gin.Context, groupService group.UseCase, userClient client.UserClient) {
+	groupID, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	usernames, err := groupService.GetMembers(groupID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := userClient.FindUsers(usernames)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, users)
+}
+
+func setMemberRole(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.MemberRolePayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse member role payload"})
+		return
+	}
+
+	g, err := groupService.SetMemberRole(util.MustGetUsername(ctx), groupId, body.Username, body.Role)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -183,32 +300,55 @@ func addMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageServi
 	ctx.JSON(http.StatusOK, groupPresenter)
 }
 
-func removeMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
-	var body payload.GroupMemberPayload
-	err := ctx.ShouldBindJSON(&body)
+func transferOwnership(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse group member payload"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
 		return
 	}
 
-	g, err := groupService.GetGroup(body.GroupID)
+	var body payload.TransferOwnershipPayload
+	err = ctx.ShouldBindJSON(&body)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get group"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse ownership payload"})
 		return
 	}
 
-	username := util.MustGetUsername(ctx)
-	if username != body.Username && username != g.OwnerName {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to remove this member"})
+	g, err := groupService.TransferOwnership(util.MustGetUsername(ctx), groupId, body.Username)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	g, err = groupService.RemoveMember(body.GroupID, body.Username)
+	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	ctx.JSON(http.StatusOK, groupPresenter)
+}
+
+func setJoinApproval(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.JoinApprovalPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse join approval payload"})
+		return
+	}
+
+	g, err := groupService.SetRequiresApproval(util.MustGetUsername(ctx), groupId, body.RequiresApproval)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -218,43 +358,329 @@ func removeMemberToGroup(ctx *gin.Context, groupService group.UseCase, messageSe
 	ctx.JSON(http.StatusOK, groupPresenter)
 }
 
-func getGroupsOfUser(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+func createInvite(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.CreateInvitePayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse invite payload"})
+		return
+	}
+
+	invite, err := groupService.CreateInvite(util.MustGetUsername(ctx), groupId, time.Duration(body.ExpiresInHours)*time.Hour, body.MaxUses)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, inviteEntityToPresenter(invite))
+}
+
+func getInvites(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	invites, err := groupService.GetInvites(util.MustGetUsername(ctx), groupId)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, inviteListEntityToPresenter(invites))
+}
+
+func revokeInvite(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	err = groupService.RevokeInvite(util.MustGetUsername(ctx), groupId, ctx.Param("code"))
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func joinByInvite(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
 	username := util.MustGetUsername(ctx)
-	pagination := util.ExtractPagination(ctx)
+	g, pending, err := groupService.JoinByInvite(username, ctx.Param("code"))
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// pending users are not members yet and only learn that their request was queued
+	if pending {
+		ctx.JSON(http.StatusAccepted, &presenter.JoinResult{Pending: true})
+		return
+	}
 
-	groups, err := groupService.GetGroupsOfUser(username, pagination)
+	groupPresenter, err := groupEntityToPresenter(g, username, groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	groupPresenters, err := groupListEntityToPresenter(groups, util.MustGetUsername(ctx), groupService, messageService, userClient)
+	ctx.JSON(http.StatusOK, &presenter.JoinResult{Group: groupPresenter})
+}
+
+func getJoinRequests(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	requests, err := groupService.GetJoinRequests(util.MustGetUsername(ctx), groupId)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, joinRequestListEntityToPresenter(requests))
+}
+
+func approveJoinRequest(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	g, err := groupService.ApproveJoinRequest(util.MustGetUsername(ctx), groupId, ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	groupPresenter, err := groupEntityToPresenter(g, util.MustGetUsername(ctx), groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, groupPresenters)
+	ctx.JSON(http.StatusOK, groupPresenter)
 }
 
-func getMembersOfGroup(ctx *gin.Context, groupService group.UseCase, userClient client.UserClient) {
-	groupID, err := strconv.Atoi(ctx.Param("groupId"))
+func declineJoinRequest(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
 		return
 	}
 
-	usernames, err := groupService.GetMembers(groupID)
+	err = groupService.DeclineJoinRequest(util.MustGetUsername(ctx), groupId, ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func getGroupIDsOfUser(ctx *gin.Context, groupService group.UseCase) {
+	groupIDs, err := groupService.GetGroupIDsOfUser(util.MustGetUsername(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	users, err := userClient.FindUsers(usernames)
+	ctx.JSON(http.StatusOK, groupIDs)
+}
+
+func muteGroup(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.MuteGroupPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil || body.Hours < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse mute payload"})
+		return
+	}
+
+	membership, err := groupService.MuteGroup(util.MustGetUsername(ctx), groupId, time.Duration(body.Hours)*time.Hour)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferencesEntityToPresenter(membership))
+}
+
+func unmuteGroup(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	membership, err := groupService.UnmuteGroup(util.MustGetUsername(ctx), groupId)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferencesEntityToPresenter(membership))
+}
+
+func archiveGroup(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.ArchiveGroupPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse archive payload"})
+		return
+	}
+
+	membership, err := groupService.SetArchived(util.MustGetUsername(ctx), groupId, body.Archived)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferencesEntityToPresenter(membership))
+}
+
+func pinGroup(ctx *gin.Context, groupService group.UseCase) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.PinGroupPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse pin payload"})
+		return
+	}
+
+	membership, err := groupService.SetPinned(util.MustGetUsername(ctx), groupId, body.Pinned)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferencesEntityToPresenter(membership))
+}
+
+func setRetention(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient, wsClient client.WsClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.RetentionPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse retention payload"})
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	g, changed, err := groupService.SetRetention(username, groupId, body.Policy, body.Days)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if changed {
+		content := "group:retention:" + string(g.RetentionPolicy)
+		if g.RetentionPolicy == entity.RetentionDays {
+			content += ":" + strconv.Itoa(g.RetentionDays)
+		}
+		muted, err := groupService.GetMutedMembers(g.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		msg, err := messageService.SendSystemMessage(username, g.ID, content)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := wsClient.SendMessage(username, presenter.MessageEntityToPresenter(msg), muted); err != nil {
+			fmt.Println("Failed to send system message:", err)
+		}
+	}
+
+	groupPresenter, err := groupEntityToPresenter(g, username, groupService, messageService, userClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	ctx.JSON(http.StatusOK, groupPresenter)
+}
+
+func setEncryption(ctx *gin.Context, groupService group.UseCase, messageService message.UseCase, userClient client.UserClient, wsClient client.WsClient) {
+	groupId, err := strconv.Atoi(ctx.Param("groupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse groupId"})
+		return
+	}
+
+	var body payload.EncryptionPayload
+	err = ctx.ShouldBindJSON(&body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot parse encryption payload"})
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	g, changed, err := groupService.SetEncrypted(username, groupId, body.Encrypted)
+	if err != nil {
+		ctx.JSON(groupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if changed {
+		content := "group:encryption:off"
+		if g.Encrypted {
+			content = "group:encryption:on"
+		}
+		muted, err := groupService.GetMutedMembers(g.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		msg, err := messageService.SendSystemMessage(username, g.ID, content)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := wsClient.SendMessage(username, presenter.MessageEntityToPresenter(msg), muted); err != nil {
+			fmt.Println("Failed to send system message:", err)
+		}
+	}
+
+	groupPresenter, err := groupEntityToPresenter(g, username, groupService, messageService, userClient)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groupPresenter)
 }
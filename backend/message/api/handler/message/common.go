@@ -1,24 +1,103 @@
 package message
 
 import (
+	"fmt"
+	"html"
+	"message/api/client"
 	"message/api/presenter"
 	"message/entity"
+	"message/usecase/message"
+	"strings"
 )
 
-func messageEntityToPresenter(in *entity.Message) (out *presenter.Message) {
-	return &presenter.Message{
-		ID:        in.ID,
-		Content:   in.Content,
-		Username:  in.Username,
-		GroupID:   in.GroupID,
-		CreatedAt: in.CreatedAt,
-	}
-}
+var highlightReplacer = strings.NewReplacer(entity.HighlightStart, "<mark>", entity.HighlightStop, "</mark>")
 
 func messageListEntityToPresenter(in []*entity.Message) (out []*presenter.Message) {
 	out = make([]*presenter.Message, 0)
 	for _, msg := range in {
-		out = append(out, messageEntityToPresenter(msg))
+		out = append(out, presenter.MessageEntityToPresenter(msg))
+	}
+	return out
+}
+
+func messagePageToPresenter(in []*entity.Message, nextCursor string) *presenter.MessagePage {
+	page := &presenter.MessagePage{
+		Messages: messageListEntityToPresenter(in),
+	}
+	if nextCursor != "" {
+		page.NextCursor = &nextCursor
+	}
+	return page
+}
+
+func messageEditListEntityToPresenter(in []*entity.MessageEdit) (out []*presenter.MessageEdit) {
+	out = make([]*presenter.MessageEdit, 0)
+	for _, edit := range in {
+		out = append(out, &presenter.MessageEdit{
+			Content:  edit.Content,
+			EditedAt: edit.EditedAt,
+		})
+	}
+	return out
+}
+
+func messageSearchPageToPresenter(in []*entity.MessageSearchResult, nextCursor string) *presenter.MessageSearchPage {
+	page := &presenter.MessageSearchPage{
+		Results: make([]*presenter.MessageSearchResult, 0, len(in)),
+	}
+	for _, result := range in {
+		page.Results = append(page.Results, &presenter.MessageSearchResult{
+			Message: presenter.MessageEntityToPresenter(result.Message),
+			Snippet: highlightReplacer.Replace(html.EscapeString(result.Snippet)),
+		})
+	}
+	if nextCursor != "" {
+		page.NextCursor = &nextCursor
+	}
+	return page
+}
+
+func chatExportEntityToPresenter(in *entity.ChatExport) *presenter.ChatExport {
+	out := &presenter.ChatExport{
+		ID:          in.ID,
+		GroupID:     in.GroupID,
+		Format:      in.Format,
+		Status:      in.Status,
+		Error:       in.Error,
+		CreatedAt:   in.CreatedAt,
+		CompletedAt: in.CompletedAt,
+		ExpiresAt:   in.ExpiresAt,
+	}
+	if in.Status == entity.ExportReady {
+		out.DownloadURL = fmt.Sprintf("/api/message/group/%d/export/%s/download", in.GroupID, in.ID)
 	}
 	return out
 }
+
+func exportFilename(groupID int, format entity.ExportFormat) string {
+	return fmt.Sprintf("chat-%d.%s", groupID, format)
+}
+
+var exportContentTypes = map[entity.ExportFormat]string{
+	entity.ExportJSON: "application/json; charset=utf-8",
+	entity.ExportHTML: "text/html; charset=utf-8",
+	entity.ExportText: "text/plain; charset=utf-8",
+}
+
+// attachLinkPreview runs in the background since fetching a preview can take seconds, members get
+// the preview in a follow up event once it is stored
+func attachLinkPreview(msg *entity.Message, messageService message.UseCase, wsClient client.WsClient) {
+	preview, err := messageService.AttachLinkPreview(msg.ID, msg.Content)
+	if err != nil {
+		fmt.Println("Failed to attach link preview:", err)
+		return
+	}
+	if preview == nil {
+		return
+	}
+
+	err = wsClient.SendLinkPreview(msg.Username, msg.ID, msg.GroupID, presenter.LinkPreviewEntityToPresenter(preview))
+	if err != nil {
+		fmt.Println("Failed to send link preview:", err)
+	}
+}
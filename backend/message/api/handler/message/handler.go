@@ -3,13 +3,14 @@ package message
 import (
 	"message/api/client"
 	"message/api/middleware"
+	"message/usecase/export"
 	"message/usecase/group"
 	"message/usecase/message"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MakeHandler(app *gin.Engine, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+func MakeHandler(app *gin.Engine, messageService message.UseCase, groupService group.UseCase, exportService export.UseCase, wsClient client.WsClient) {
 	messageGroup := app.Group("/api/message")
 	{
 		messageGroup.Use(middleware.MustAuthMiddleware())
@@ -18,12 +19,52 @@ func MakeHandler(app *gin.Engine, messageService message.UseCase, groupService g
 			getGroupMessageList(ctx, messageService, groupService)
 		})
 
+		messageGroup.GET("/group/:groupID/export", func(ctx *gin.Context) {
+			exportChat(ctx, exportService, groupService)
+		})
+
+		messageGroup.GET("/group/:groupID/export/:exportID", func(ctx *gin.Context) {
+			getChatExport(ctx, exportService, groupService)
+		})
+
+		messageGroup.GET("/group/:groupID/export/:exportID/download", func(ctx *gin.Context) {
+			downloadChatExport(ctx, exportService, groupService)
+		})
+
 		messageGroup.GET("/direct", func(ctx *gin.Context) {
 			getDirectMessageList(ctx, messageService)
 		})
 
+		messageGroup.GET("/search", func(ctx *gin.Context) {
+			searchMessages(ctx, messageService)
+		})
+
 		messageGroup.POST("/group/:groupID", func(ctx *gin.Context) {
 			createGroupMessage(ctx, messageService, groupService, wsClient)
 		})
+
+		messageGroup.POST("/group/:groupID/read", func(ctx *gin.Context) {
+			markGroupAsRead(ctx, messageService, groupService, wsClient)
+		})
+
+		messageGroup.GET("/:id/history", func(ctx *gin.Context) {
+			getMessageHistory(ctx, messageService, groupService)
+		})
+
+		messageGroup.PATCH("/:id", func(ctx *gin.Context) {
+			editMessage(ctx, messageService, groupService, wsClient)
+		})
+
+		messageGroup.DELETE("/:id", func(ctx *gin.Context) {
+			deleteMessage(ctx, messageService, groupService, wsClient)
+		})
+
+		messageGroup.POST("/:id/reactions", func(ctx *gin.Context) {
+			upsertReaction(ctx, messageService, groupService, wsClient)
+		})
+
+		messageGroup.DELETE("/:id/reactions", func(ctx *gin.Context) {
+			deleteReaction(ctx, messageService, groupService, wsClient)
+		})
 	}
 }
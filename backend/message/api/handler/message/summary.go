@@ -1,8 +1,13 @@
 package message
 
 import (
+	"errors"
+	"fmt"
 	"message/api/client"
 	payload "message/api/payload/message"
+	"message/api/presenter"
+	"message/entity"
+	"message/usecase/export"
 	"message/usecase/group"
 	"message/usecase/message"
 	"message/util"
@@ -12,16 +17,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func checkMembership(ctx *gin.Context, groupID int, groupService group.UseCase) {
+func checkMembership(ctx *gin.Context, groupID int, groupService group.UseCase) bool {
 	membership, err := groupService.CheckMembership(ctx.MustGet("username").(string), groupID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return false
 	}
 	if !membership {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
-		return
+		return false
 	}
+	return true
 }
 
 func getGroupMessageList(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase) {
@@ -33,15 +39,23 @@ func getGroupMessageList(ctx *gin.Context, messageService message.UseCase, group
 		return
 	}
 
-	checkMembership(ctx, groupID, groupService)
+	if !checkMembership(ctx, groupID, groupService) {
+		return
+	}
+
+	pagination, err := util.ExtractCursorPagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	messages, err := messageService.GetGroupMessageList(groupID, util.ExtractPagination(ctx))
+	messages, nextCursor, err := messageService.GetGroupMessageList(groupID, pagination)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, messageListEntityToPresenter(messages))
+	ctx.JSON(http.StatusOK, messagePageToPresenter(messages, nextCursor))
 }
 
 func getDirectMessageList(ctx *gin.Context, messageService message.UseCase) {
@@ -61,6 +75,44 @@ func getDirectMessageList(ctx *gin.Context, messageService message.UseCase) {
 	ctx.JSON(http.StatusOK, messageListEntityToPresenter(messages))
 }
 
+func searchMessages(ctx *gin.Context, messageService message.UseCase) {
+	var groupID *int
+	if groupIdQuery := ctx.Query("groupId"); groupIdQuery != "" {
+		id, err := strconv.Atoi(groupIdQuery)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		groupID = &id
+	}
+
+	pagination, err := util.ExtractCursorPagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if pagination.Direction == util.After {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Search results can only be paged with before"})
+		return
+	}
+
+	results, nextCursor, err := messageService.SearchMessages(util.MustGetUsername(ctx), ctx.Query("q"), groupID, pagination)
+	if errors.Is(err, message.ErrEmptyQuery) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, message.ErrNotInGroup) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, messageSearchPageToPresenter(results, nextCursor))
+}
+
 func createGroupMessage(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
 	groupIdParam := ctx.Param("groupID")
 	groupID, err := strconv.Atoi(groupIdParam)
@@ -75,15 +127,385 @@ func createGroupMessage(ctx *gin.Context, messageService message.UseCase, groupS
 		return
 	}
 
-	checkMembership(ctx, groupID, groupService)
+	canPost, err := groupService.CanPost(util.MustGetUsername(ctx), groupID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canPost {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to post in this group"})
+		return
+	}
+
+	var msg *entity.Message
+	if body.Encrypted != nil {
+		if body.Content != "" || len(body.Attachments) > 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Encrypted messages cannot carry plaintext content or attachments"})
+			return
+		}
+		msg, err = messageService.SendEncryptedMessage(util.MustGetUsername(ctx), groupID, body.Encrypted, body.ReplyToMessageID)
+	} else {
+		attachments := payload.AttachmentListPayloadToEntity(body.Attachments)
+		msg, err = messageService.SendMessage(util.MustGetUsername(ctx), body.Content, groupID, attachments, body.ReplyToMessageID)
+	}
+	if errors.Is(err, message.ErrEmptyMessage) || errors.Is(err, message.ErrInvalidAttachment) || errors.Is(err, message.ErrInvalidReply) || errors.Is(err, message.ErrInvalidEnvelope) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, message.ErrEncryptedGroup) || errors.Is(err, message.ErrNotEncrypted) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	muted, err := groupService.GetMutedMembers(groupID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	messagePresenter := presenter.MessageEntityToPresenter(msg)
+	if err := wsClient.SendMessage(ctx.MustGet("username").(string), messagePresenter, muted); err != nil {
+		fmt.Println("Failed to send message:", err)
+	}
+	go attachLinkPreview(msg, messageService, wsClient)
+
+	ctx.JSON(http.StatusCreated, messagePresenter)
+}
+
+func markGroupAsRead(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+	groupIdParam := ctx.Param("groupID")
+	groupID, err := strconv.Atoi(groupIdParam)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body payload.ReadMessagePayload
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkMembership(ctx, groupID, groupService) {
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	lastRead, err := messageService.MarkAsRead(username, groupID, body.MessageID)
+	if errors.Is(err, message.ErrMessageNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := wsClient.SendRead(username, groupID, lastRead); err != nil {
+		fmt.Println("Failed to send read receipt:", err)
+	}
+
+	ctx.JSON(http.StatusOK, &presenter.ReadReceipt{
+		GroupID:   groupID,
+		Username:  username,
+		MessageID: lastRead,
+	})
+}
+
+func getMessageHistory(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase) {
+	messageID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := messageService.GetMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !checkMembership(ctx, msg.GroupID, groupService) {
+		return
+	}
+
+	edits, err := messageService.GetMessageHistory(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, messageEditListEntityToPresenter(edits))
+}
+
+func editMessage(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+	messageID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body payload.EditMessagePayload
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.Content == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Message content is empty"})
+		return
+	}
+
+	msg, err := messageService.GetMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !checkMembership(ctx, msg.GroupID, groupService) {
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	if msg.Username != username {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to edit this message"})
+		return
+	}
+
+	msg, err = messageService.EditMessage(messageID, body.Content)
+	if errors.Is(err, message.ErrMessageDeleted) || errors.Is(err, message.ErrSystemMessage) || errors.Is(err, message.ErrEncryptedMessage) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := wsClient.SendEdit(username, msg.ID, msg.GroupID, msg.Content, *msg.EditedAt); err != nil {
+		fmt.Println("Failed to send message edit:", err)
+	}
+	go attachLinkPreview(msg, messageService, wsClient)
+
+	ctx.JSON(http.StatusOK, presenter.MessageEntityToPresenter(msg))
+}
+
+func deleteMessage(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+	messageID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := messageService.GetMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !checkMembership(ctx, msg.GroupID, groupService) {
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	if msg.Username != username {
+		canModerate, err := groupService.CanModerate(username, msg.GroupID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !canModerate {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to delete this message"})
+			return
+		}
+	}
+
+	msg, err = messageService.DeleteMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := wsClient.SendDelete(username, msg.ID, msg.GroupID); err != nil {
+		fmt.Println("Failed to send message deletion:", err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func upsertReaction(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+	messageID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body payload.ReactionPayload
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := messageService.GetMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
 
-	msg, err := messageService.SendMessage(ctx.MustGet("username").(string), body.Content, groupID)
+	if !checkMembership(ctx, msg.GroupID, groupService) {
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	msg, err = messageService.UpsertReaction(messageID, username, body.Type)
+	if errors.Is(err, message.ErrInvalidReaction) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, message.ErrMessageDeleted) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	wsClient.SendMessage(msg.ID, ctx.MustGet("username").(string), groupID, body.Content, msg.CreatedAt)
+	if err := wsClient.SendReaction(username, msg.ID, msg.GroupID, body.Type); err != nil {
+		fmt.Println("Failed to send reaction:", err)
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+func deleteReaction(ctx *gin.Context, messageService message.UseCase, groupService group.UseCase, wsClient client.WsClient) {
+	messageID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := messageService.GetMessage(messageID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !checkMembership(ctx, msg.GroupID, groupService) {
+		return
+	}
+
+	username := util.MustGetUsername(ctx)
+	msg, err = messageService.DeleteReaction(messageID, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := wsClient.SendReaction(username, msg.ID, msg.GroupID, ""); err != nil {
+		fmt.Println("Failed to send reaction removal:", err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// exportChat streams the history of small groups right away, larger ones are exported in the
+// background and answered with 202 and the export to poll
+func exportChat(ctx *gin.Context, exportService export.UseCase, groupService group.UseCase) {
+	groupID, err := strconv.Atoi(ctx.Param("groupID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := export.ParseFormat(ctx.Query("format"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkMembership(ctx, groupID, groupService) {
+		return
+	}
+
+	large, err := exportService.IsLarge(groupID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if large {
+		job, err := exportService.StartExport(util.MustGetUsername(ctx), groupID, format)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusAccepted, chatExportEntityToPresenter(job))
+		return
+	}
+
+	ctx.Header("Content-Type", exportContentTypes[format])
+	ctx.Header("Content-Disposition", `attachment; filename="`+exportFilename(groupID, format)+`"`)
+	ctx.Status(http.StatusOK)
+	if err := exportService.Write(ctx.Writer, groupID, format); err != nil {
+		// Headers are already sent, all that is left is to cut the download short
+		fmt.Println("Failed to export chat:", err)
+		ctx.Abort()
+	}
+}
+
+func getChatExport(ctx *gin.Context, exportService export.UseCase, groupService group.UseCase) {
+	groupID, err := strconv.Atoi(ctx.Param("groupID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkMembership(ctx, groupID, groupService) {
+		return
+	}
+
+	job, err := exportService.GetExport(util.MustGetUsername(ctx), groupID, ctx.Param("exportID"))
+	if errors.Is(err, export.ErrExportNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, chatExportEntityToPresenter(job))
+}
+
+func downloadChatExport(ctx *gin.Context, exportService export.UseCase, groupService group.UseCase) {
+	groupID, err := strconv.Atoi(ctx.Param("groupID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !checkMembership(ctx, groupID, groupService) {
+		return
+	}
+
+	job, err := exportService.GetExportFile(util.MustGetUsername(ctx), groupID, ctx.Param("exportID"))
+	if errors.Is(err, export.ErrExportNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, export.ErrExportNotReady) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	ctx.JSON(http.StatusCreated, messageEntityToPresenter(msg))
+	ctx.Header("Content-Disposition", `attachment; filename="`+exportFilename(groupID, job.Format)+`"`)
+	ctx.Header("Content-Type", exportContentTypes[job.Format])
+	ctx.File(job.Path)
 }
@@ -1,12 +1,19 @@
 package payload
 
+import "message/entity"
+
 type CreateGroupPayload struct {
 	GroupName string   `json:"group_name"`
 	Members   []string `json:"members"`
 }
 
+// Omitted fields are left unchanged
 type UpdateGroupPayload struct {
-	GroupName string `json:"group_name"`
+	GroupName          *string `json:"group_name"`
+	Avatar             *string `json:"avatar"`
+	Description        *string `json:"description"`
+	OnlyAdminsPost     *bool   `json:"only_admins_post"`
+	OnlyAdminsEditInfo *bool   `json:"only_admins_edit_info"`
 }
 
 type DeleteGroupPayload struct {
@@ -17,3 +24,45 @@ type GroupMemberPayload struct {
 	GroupID  int    `json:"group_id"`
 	Username string `json:"username"`
 }
+
+type MemberRolePayload struct {
+	Username string           `json:"username"`
+	Role     entity.GroupRole `json:"role"`
+}
+
+type TransferOwnershipPayload struct {
+	Username string `json:"username"`
+}
+
+// Zero values mean the invite never expires and can be used any number of times
+type CreateInvitePayload struct {
+	ExpiresInHours int `json:"expires_in_hours"`
+	MaxUses        int `json:"max_uses"`
+}
+
+// Hours of 0 mutes the group until it is unmuted
+type MuteGroupPayload struct {
+	Hours int `json:"hours"`
+}
+
+type ArchiveGroupPayload struct {
+	Archived bool `json:"archived"`
+}
+
+type PinGroupPayload struct {
+	Pinned bool `json:"pinned"`
+}
+
+type JoinApprovalPayload struct {
+	RequiresApproval bool `json:"requires_approval"`
+}
+
+// RetentionDays is only read for the "days" policy
+type RetentionPayload struct {
+	Policy entity.RetentionPolicy `json:"policy"`
+	Days   int                    `json:"days"`
+}
+
+type EncryptionPayload struct {
+	Encrypted bool `json:"encrypted"`
+}
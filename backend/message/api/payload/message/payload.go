@@ -1,10 +1,67 @@
 package payload
 
+import (
+	"message/e2ee"
+	"message/entity"
+)
+
+type AttachmentPayload struct {
+	Type      entity.AttachmentType `json:"type"`
+	URL       string                `json:"url"`
+	Name      string                `json:"name"`
+	MimeType  string                `json:"mime_type"`
+	Size      int64                 `json:"size"`
+	Latitude  *float64              `json:"latitude"`
+	Longitude *float64              `json:"longitude"`
+	PostID    string                `json:"post_id"`
+}
+
 type CreateMessagePayload struct {
+	Content          string              `json:"content"`
+	Attachments      []AttachmentPayload `json:"attachments"`
+	ReplyToMessageID *int                `json:"reply_to_message_id"`
+
+	// Sent instead of content and attachments in groups with end-to-end encryption
+	Encrypted *e2ee.EncryptedMessage `json:"encrypted"`
+}
+
+type EditMessagePayload struct {
 	Content string `json:"content"`
 }
 
+type ReadMessagePayload struct {
+	// Zero marks every message of the group as read
+	MessageID int `json:"message_id"`
+}
+
 type CreateDirectMessagePayload struct {
-	OppUsername string `json:"opp_username"`
-	Content     string `json:"content"`
+	OppUsername      string              `json:"opp_username"`
+	Content          string              `json:"content"`
+	Attachments      []AttachmentPayload `json:"attachments"`
+	ReplyToMessageID *int                `json:"reply_to_message_id"`
+}
+
+type ReactionPayload struct {
+	Type string `json:"type"`
+}
+
+func (p AttachmentPayload) ToEntity() *entity.Attachment {
+	return &entity.Attachment{
+		Type:      p.Type,
+		URL:       p.URL,
+		Name:      p.Name,
+		MimeType:  p.MimeType,
+		Size:      p.Size,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		PostID:    p.PostID,
+	}
+}
+
+func AttachmentListPayloadToEntity(in []AttachmentPayload) []*entity.Attachment {
+	out := make([]*entity.Attachment, 0, len(in))
+	for _, a := range in {
+		out = append(out, a.ToEntity())
+	}
+	return out
 }
@@ -0,0 +1,19 @@
+package presenter
+
+import (
+	"message/entity"
+	"time"
+)
+
+type ChatExport struct {
+	ID          string              `json:"id"`
+	GroupID     int                 `json:"group_id"`
+	Format      entity.ExportFormat `json:"format"`
+	Status      entity.ExportStatus `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	CompletedAt *time.Time          `json:"completed_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	// Set once the export is ready
+	DownloadURL string `json:"download_url,omitempty"`
+}
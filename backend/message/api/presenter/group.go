@@ -1,5 +1,10 @@
 package presenter
 
+import (
+	"message/entity"
+	"time"
+)
+
 type User struct {
 	Username    string `json:"username"`
 	DisplayName string `json:"displayName"`
@@ -11,11 +16,60 @@ type Group struct {
 	Name        string   `json:"name"`
 	IsDirect    bool     `json:"is_direct"`
 	LastMessage *Message `json:"last_message"`
+	UnreadCount int      `json:"unread_count"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Preferences of the requesting user
+	Preferences *GroupPreferences `json:"preferences"`
+
+	// Undirect settings
+	RequiresApproval   bool   `json:"requires_approval"`
+	OnlyAdminsPost     bool   `json:"only_admins_post"`
+	OnlyAdminsEditInfo bool   `json:"only_admins_edit_info"`
+	Description        string `json:"description"`
+
+	// Direct groups only
+	Encrypted bool `json:"encrypted"`
+
+	RetentionPolicy entity.RetentionPolicy `json:"retention_policy"`
+	RetentionDays   int                    `json:"retention_days"`
+
+	// Last read message ID of each member, for "seen by" markers
+	LastReads map[string]int `json:"last_reads"`
 
 	// Undirect
-	OwnerName string   `json:"owner_name"`
-	Members   []string `json:"members"`
+	OwnerName string                      `json:"owner_name"`
+	Members   []string                    `json:"members"`
+	Roles     map[string]entity.GroupRole `json:"roles"`
 
-	// Direct
+	// The group's own avatar, or the other user's avatar for direct groups
 	Avatar string `json:"avatar"`
 }
+
+type GroupPreferences struct {
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until"`
+	Archived   bool       `json:"archived"`
+	Pinned     bool       `json:"pinned"`
+}
+
+type GroupInvite struct {
+	Code      string     `json:"code"`
+	GroupID   int        `json:"group_id"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type JoinRequest struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type JoinResult struct {
+	Pending bool   `json:"pending"`
+	Group   *Group `json:"group"`
+}
@@ -1,11 +1,190 @@
 package presenter
 
-import "time"
+import (
+	"message/e2ee"
+	"message/entity"
+	"time"
+	"unicode/utf8"
+)
+
+const replySnippetLength = 100
 
 type Message struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	GroupID   int       `json:"group_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	Username  string     `json:"username"`
+	GroupID   int        `json:"group_id"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Deleted   bool       `json:"deleted"`
+	System    bool       `json:"system"`
+
+	Protocol  entity.MessageProtocol `json:"protocol"`
+	Encrypted *e2ee.EncryptedMessage `json:"encrypted,omitempty"`
+
+	ReplyTo     *MessageReply `json:"reply_to"`
+	Attachments []*Attachment `json:"attachments"`
+	LinkPreview *LinkPreview  `json:"link_preview"`
+	Reactions   []*Reaction   `json:"reactions"`
+}
+
+// MessageReply quotes the message being replied to
+type MessageReply struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Snippet  string `json:"snippet"`
+	Deleted  bool   `json:"deleted"`
+}
+
+type Reaction struct {
+	Username string `json:"username"`
+	Type     string `json:"type"`
+}
+
+type Attachment struct {
+	ID        int                   `json:"id"`
+	Type      entity.AttachmentType `json:"type"`
+	URL       string                `json:"url,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	MimeType  string                `json:"mime_type,omitempty"`
+	Size      int64                 `json:"size,omitempty"`
+	Latitude  *float64              `json:"latitude,omitempty"`
+	Longitude *float64              `json:"longitude,omitempty"`
+	PostID    string                `json:"post_id,omitempty"`
+}
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+// MessageSearchResult carries an html escaped snippet in which matches are wrapped in <mark>
+type MessageSearchResult struct {
+	Message *Message `json:"message"`
+	Snippet string   `json:"snippet"`
+}
+
+type MessageSearchPage struct {
+	Results    []*MessageSearchResult `json:"results"`
+	NextCursor *string                `json:"next_cursor"`
+}
+
+type MessagePage struct {
+	Messages   []*Message `json:"messages"`
+	NextCursor *string    `json:"next_cursor"`
+}
+
+type MessageEdit struct {
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type ReadReceipt struct {
+	GroupID   int    `json:"group_id"`
+	Username  string `json:"username"`
+	MessageID int    `json:"message_id"`
+}
+
+// MessageEntityToPresenter is shared by the message and group handlers
+func MessageEntityToPresenter(in *entity.Message) *Message {
+	return &Message{
+		ID:        in.ID,
+		Content:   in.Content,
+		Username:  in.Username,
+		GroupID:   in.GroupID,
+		CreatedAt: in.CreatedAt,
+		EditedAt:  in.EditedAt,
+		Deleted:   in.DeletedAt != nil,
+		System:    in.System,
+
+		Protocol:  in.Protocol,
+		Encrypted: EncryptedMessageEntityToPresenter(in),
+
+		ReplyTo:     MessageReplyEntityToPresenter(in.ReplyTo),
+		Attachments: AttachmentListEntityToPresenter(in.Attachments),
+		LinkPreview: LinkPreviewEntityToPresenter(in.LinkPreview),
+		Reactions:   ReactionListEntityToPresenter(in.Reactions),
+	}
+}
+
+func AttachmentListEntityToPresenter(in []*entity.Attachment) []*Attachment {
+	out := make([]*Attachment, 0, len(in))
+	for _, a := range in {
+		out = append(out, &Attachment{
+			ID:        a.ID,
+			Type:      a.Type,
+			URL:       a.URL,
+			Name:      a.Name,
+			MimeType:  a.MimeType,
+			Size:      a.Size,
+			Latitude:  a.Latitude,
+			Longitude: a.Longitude,
+			PostID:    a.PostID,
+		})
+	}
+	return out
+}
+
+func LinkPreviewEntityToPresenter(in *entity.LinkPreview) *LinkPreview {
+	if in == nil {
+		return nil
+	}
+	return &LinkPreview{
+		URL:         in.URL,
+		Title:       in.Title,
+		Description: in.Description,
+		Image:       in.Image,
+	}
+}
+
+func MessageReplyEntityToPresenter(in *entity.Message) *MessageReply {
+	if in == nil {
+		return nil
+	}
+	snippet := in.Content
+	if utf8.RuneCountInString(snippet) > replySnippetLength {
+		snippet = string([]rune(snippet)[:replySnippetLength]) + "…"
+	}
+	return &MessageReply{
+		ID:       in.ID,
+		Username: in.Username,
+		Snippet:  snippet,
+		Deleted:  in.DeletedAt != nil,
+	}
+}
+
+// EncryptedMessageEntityToPresenter gives clients back the envelopes as they were sent, nil for plaintext messages
+func EncryptedMessageEntityToPresenter(in *entity.Message) *e2ee.EncryptedMessage {
+	if in.Protocol != entity.ProtocolE2EE || in.DeletedAt != nil {
+		return nil
+	}
+	out := &e2ee.EncryptedMessage{
+		Version:      in.ProtocolVersion,
+		SenderDevice: in.SenderDevice,
+		Envelopes:    make([]e2ee.Envelope, 0, len(in.Envelopes)),
+	}
+	for _, e := range in.Envelopes {
+		out.Envelopes = append(out.Envelopes, e2ee.Envelope{
+			Recipient:    e.Recipient,
+			DeviceID:     e.DeviceID,
+			EphemeralKey: e.EphemeralKey,
+			Nonce:        e.Nonce,
+			Ciphertext:   e.Ciphertext,
+			Signature:    e.Signature,
+		})
+	}
+	return out
+}
+
+func ReactionListEntityToPresenter(in []*entity.MessageReaction) []*Reaction {
+	out := make([]*Reaction, 0, len(in))
+	for _, r := range in {
+		out = append(out, &Reaction{
+			Username: r.Username,
+			Type:     r.Type,
+		})
+	}
+	return out
 }
@@ -0,0 +1,134 @@
+package e2ee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+var ErrSenderDeviceNotFound = errors.New("e2ee: sender device is not registered")
+
+// Message is the part of a message returned by the message service that decryption needs
+type Message struct {
+	ID        int               `json:"id"`
+	Username  string            `json:"username"`
+	GroupID   int               `json:"group_id"`
+	Protocol  string            `json:"protocol"`
+	Encrypted *EncryptedMessage `json:"encrypted"`
+}
+
+// Client talks to the API gateway on behalf of one signed in device
+type Client struct {
+	baseUrl string
+	token   string
+	device  *Device
+	http    *http.Client
+}
+
+// NewClient signs in as the user who owns the device
+func NewClient(baseUrl string, token string, device *Device) *Client {
+	return &Client{
+		baseUrl: baseUrl,
+		token:   token,
+		device:  device,
+		http:    &http.Client{},
+	}
+}
+
+// RegisterDevice publishes the bundle of the client's device, replacing an older one with the same ID
+func (c *Client) RegisterDevice() error {
+	return c.do("PUT", "/api/user/keys", c.device.Bundle(), nil)
+}
+
+func (c *Client) FetchBundles(username string) ([]Bundle, error) {
+	var bundles []Bundle
+	if err := c.do("GET", "/api/user/"+username+"/keys", nil, &bundles); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// SendDirect seals plaintext for every device of the peer and for the user's own other devices,
+// then posts it to the direct group, which must have encryption enabled
+func (c *Client) SendDirect(groupID int, peer string, plaintext []byte) (*Message, error) {
+	var recipients []Recipient
+	for _, username := range []string{peer, c.device.Username} {
+		bundles, err := c.FetchBundles(username)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bundles {
+			if username == c.device.Username && b.DeviceID == c.device.ID {
+				continue
+			}
+			recipients = append(recipients, Recipient{Username: username, Bundle: b})
+		}
+	}
+
+	encrypted, err := c.device.Seal(plaintext, recipients)
+	if err != nil {
+		return nil, err
+	}
+	var msg Message
+	body := map[string]interface{}{"encrypted": encrypted}
+	if err := c.do("POST", "/api/message/group/"+strconv.Itoa(groupID), body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Decrypt opens the envelope addressed to the client's device, checking it against the sender's
+// registered bundle
+func (c *Client) Decrypt(msg *Message) ([]byte, error) {
+	if msg.Protocol != Protocol || msg.Encrypted == nil {
+		return nil, ErrInvalidEnvelope
+	}
+	envelope, ok := msg.Encrypted.EnvelopeFor(c.device.Username, c.device.ID)
+	if !ok {
+		return nil, ErrWrongDevice
+	}
+
+	bundles, err := c.FetchBundles(msg.Username)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bundles {
+		if b.DeviceID == msg.Encrypted.SenderDevice {
+			return c.device.Open(msg.Username, b, envelope)
+		}
+	}
+	return nil, ErrSenderDeviceNotFound
+}
+
+func (c *Client) do(method string, path string, in interface{}, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseUrl+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("e2ee: %s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
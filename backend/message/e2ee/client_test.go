@@ -0,0 +1,90 @@
+package e2ee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGateway keeps bundles and messages in memory, users are identified by their bearer token
+type fakeGateway struct {
+	mu       sync.Mutex
+	bundles  map[string][]Bundle
+	messages []*Message
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	username := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	switch {
+	case r.Method == "PUT" && r.URL.Path == "/api/user/keys":
+		var b Bundle
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil || b.Verify() != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		g.bundles[username] = append(g.bundles[username], b)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/keys"):
+		owner := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/user/"), "/keys")
+		json.NewEncoder(w).Encode(g.bundles[owner])
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/api/message/group/"):
+		var body struct {
+			Encrypted *EncryptedMessage `json:"encrypted"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Encrypted.Validate() != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msg := &Message{ID: len(g.messages) + 1, Username: username, GroupID: 1, Protocol: Protocol, Encrypted: body.Encrypted}
+		g.messages = append(g.messages, msg)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(msg)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClientSendDirect(t *testing.T) {
+	gateway := &fakeGateway{bundles: map[string][]Bundle{}}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	newClient := func(username, deviceID string) *Client {
+		c := NewClient(server.URL, username, mustDevice(t, username, deviceID))
+		if err := c.RegisterDevice(); err != nil {
+			t.Fatalf("RegisterDevice(%s): %v", deviceID, err)
+		}
+		return c
+	}
+	// Both users have a "phone", device IDs are only unique per user
+	alicePhone := newClient("alice", "phone")
+	aliceLaptop := newClient("alice", "laptop")
+	bobPhone := newClient("bob", "phone")
+
+	sent, err := alicePhone.SendDirect(1, "bob", []byte("walk at 6?"))
+	if err != nil {
+		t.Fatalf("SendDirect: %v", err)
+	}
+	if len(sent.Encrypted.Envelopes) != 2 {
+		t.Fatalf("got %d envelopes, want one for bob and one for alice's laptop", len(sent.Encrypted.Envelopes))
+	}
+
+	for _, c := range []*Client{bobPhone, aliceLaptop} {
+		got, err := c.Decrypt(sent)
+		if err != nil {
+			t.Fatalf("%s Decrypt: %v", c.device.ID, err)
+		}
+		if string(got) != "walk at 6?" {
+			t.Fatalf("%s decrypted %q", c.device.ID, got)
+		}
+	}
+	if _, err := alicePhone.Decrypt(sent); err != ErrWrongDevice {
+		t.Fatalf("sender device Decrypt: got %v, want ErrWrongDevice", err)
+	}
+}
@@ -0,0 +1,351 @@
+// Package e2ee is the reference implementation of end-to-end encrypted direct messages.
+//
+// Every device owns an Ed25519 identity key and an X25519 pre-key signed by it, published as a
+// Bundle through the user service. Device IDs are only unique per user, so a device is always
+// addressed by its user and its ID together. A message is sealed once per recipient device: the sender
+// generates an ephemeral X25519 key, derives an AES-256-GCM key from the shared secret with
+// HKDF-SHA256 and signs the envelope with its identity key. The servers only store and relay
+// envelopes, they never see a key able to open them.
+package e2ee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// Protocol is the value of entity.Message.Protocol for encrypted messages
+	Protocol = "e2ee"
+	// Version is bumped whenever the envelope format or the key derivation changes
+	Version = 2
+
+	MaxDeviceIDLength = 64
+
+	kdfInfo = "furbook-e2ee-v1"
+)
+
+var (
+	ErrInvalidBundle      = errors.New("e2ee: invalid key bundle")
+	ErrInvalidEnvelope    = errors.New("e2ee: invalid envelope")
+	ErrInvalidDevice      = errors.New("e2ee: invalid device state")
+	ErrBadSignature       = errors.New("e2ee: signature does not match the sender identity")
+	ErrWrongDevice        = errors.New("e2ee: envelope is addressed to another device")
+	ErrUnsupportedVersion = errors.New("e2ee: unsupported protocol version")
+)
+
+var encoding = base64.StdEncoding
+
+// Bundle is the public half of a device, as registered with the user service
+type Bundle struct {
+	DeviceID        string `json:"deviceId"`
+	IdentityKey     string `json:"identityKey"`
+	PreKey          string `json:"preKey"`
+	PreKeySignature string `json:"preKeySignature"`
+}
+
+// Verify checks that the pre-key was signed by the identity key of the bundle
+func (b Bundle) Verify() error {
+	_, _, err := b.decode()
+	return err
+}
+
+func (b Bundle) decode() (ed25519.PublicKey, *ecdh.PublicKey, error) {
+	if b.DeviceID == "" || len(b.DeviceID) > MaxDeviceIDLength {
+		return nil, nil, ErrInvalidBundle
+	}
+	identity, err := encoding.DecodeString(b.IdentityKey)
+	if err != nil || len(identity) != ed25519.PublicKeySize {
+		return nil, nil, ErrInvalidBundle
+	}
+	preKeyBytes, err := encoding.DecodeString(b.PreKey)
+	if err != nil {
+		return nil, nil, ErrInvalidBundle
+	}
+	preKey, err := ecdh.X25519().NewPublicKey(preKeyBytes)
+	if err != nil {
+		return nil, nil, ErrInvalidBundle
+	}
+	signature, err := encoding.DecodeString(b.PreKeySignature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, nil, ErrInvalidBundle
+	}
+	if !ed25519.Verify(identity, preKeyBytes, signature) {
+		return nil, nil, ErrBadSignature
+	}
+	return identity, preKey, nil
+}
+
+// Envelope carries a message sealed for a single device
+type Envelope struct {
+	Recipient    string `json:"recipient"`
+	DeviceID     string `json:"device_id"`
+	EphemeralKey string `json:"ephemeral_key"`
+	Nonce        string `json:"nonce"`
+	Ciphertext   string `json:"ciphertext"`
+	Signature    string `json:"signature"`
+}
+
+// Validate checks the shape of the envelope without any key, which is all the server can do
+func (e Envelope) Validate() error {
+	_, _, _, _, err := e.decode()
+	return err
+}
+
+func (e Envelope) decode() (ephemeralKey, nonce, ciphertext, signature []byte, err error) {
+	if e.Recipient == "" || e.DeviceID == "" || len(e.DeviceID) > MaxDeviceIDLength {
+		return nil, nil, nil, nil, ErrInvalidEnvelope
+	}
+	ephemeralKey, err = encoding.DecodeString(e.EphemeralKey)
+	if err != nil || len(ephemeralKey) != 32 {
+		return nil, nil, nil, nil, ErrInvalidEnvelope
+	}
+	nonce, err = encoding.DecodeString(e.Nonce)
+	if err != nil || len(nonce) != 12 {
+		return nil, nil, nil, nil, ErrInvalidEnvelope
+	}
+	// GCM appends a 16 byte tag, so even an empty plaintext has that much ciphertext
+	ciphertext, err = encoding.DecodeString(e.Ciphertext)
+	if err != nil || len(ciphertext) < 16 {
+		return nil, nil, nil, nil, ErrInvalidEnvelope
+	}
+	signature, err = encoding.DecodeString(e.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, nil, nil, nil, ErrInvalidEnvelope
+	}
+	return ephemeralKey, nonce, ciphertext, signature, nil
+}
+
+// EncryptedMessage is what a client sends in place of plaintext content
+type EncryptedMessage struct {
+	Version      int        `json:"version"`
+	SenderDevice string     `json:"sender_device"`
+	Envelopes    []Envelope `json:"envelopes"`
+}
+
+// Validate checks the version and every envelope, and that no device got two envelopes
+func (m *EncryptedMessage) Validate() error {
+	if m.Version != Version {
+		return ErrUnsupportedVersion
+	}
+	if m.SenderDevice == "" || len(m.SenderDevice) > MaxDeviceIDLength || len(m.Envelopes) == 0 {
+		return ErrInvalidEnvelope
+	}
+	seen := make(map[string]bool, len(m.Envelopes))
+	for _, e := range m.Envelopes {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		key := e.Recipient + "/" + e.DeviceID
+		if seen[key] {
+			return ErrInvalidEnvelope
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// EnvelopeFor finds the envelope sealed for the given device of the given user
+func (m *EncryptedMessage) EnvelopeFor(username string, deviceID string) (Envelope, bool) {
+	for _, e := range m.Envelopes {
+		if e.Recipient == username && e.DeviceID == deviceID {
+			return e, true
+		}
+	}
+	return Envelope{}, false
+}
+
+// Device holds the private keys of one of the user's devices
+type Device struct {
+	Username string
+	ID       string
+	identity ed25519.PrivateKey
+	preKey   *ecdh.PrivateKey
+}
+
+func NewDevice(username string, id string) (*Device, error) {
+	if username == "" || id == "" || len(id) > MaxDeviceIDLength {
+		return nil, ErrInvalidDevice
+	}
+	_, identity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	preKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Device{Username: username, ID: id, identity: identity, preKey: preKey}, nil
+}
+
+// Bundle returns the public keys to register for this device
+func (d *Device) Bundle() Bundle {
+	preKey := d.preKey.PublicKey().Bytes()
+	return Bundle{
+		DeviceID:        d.ID,
+		IdentityKey:     encoding.EncodeToString(d.identity.Public().(ed25519.PublicKey)),
+		PreKey:          encoding.EncodeToString(preKey),
+		PreKeySignature: encoding.EncodeToString(ed25519.Sign(d.identity, preKey)),
+	}
+}
+
+type deviceState struct {
+	Username string `json:"username"`
+	ID       string `json:"id"`
+	Identity []byte `json:"identity"`
+	PreKey   []byte `json:"pre_key"`
+}
+
+// MarshalJSON exports the private keys so the device can be restored later, keep the output secret
+func (d *Device) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&deviceState{
+		Username: d.Username,
+		ID:       d.ID,
+		Identity: d.identity.Seed(),
+		PreKey:   d.preKey.Bytes(),
+	})
+}
+
+func (d *Device) UnmarshalJSON(data []byte) error {
+	var state deviceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Username == "" || state.ID == "" || len(state.ID) > MaxDeviceIDLength || len(state.Identity) != ed25519.SeedSize {
+		return ErrInvalidDevice
+	}
+	preKey, err := ecdh.X25519().NewPrivateKey(state.PreKey)
+	if err != nil {
+		return ErrInvalidDevice
+	}
+	d.Username = state.Username
+	d.ID = state.ID
+	d.identity = ed25519.NewKeyFromSeed(state.Identity)
+	d.preKey = preKey
+	return nil
+}
+
+// Recipient is a device to seal a message for
+type Recipient struct {
+	Username string
+	Bundle   Bundle
+}
+
+// Seal encrypts plaintext once per recipient device. Bundles are verified first, a pre-key that
+// was not signed by its identity key is never encrypted to
+func (d *Device) Seal(plaintext []byte, recipients []Recipient) (*EncryptedMessage, error) {
+	msg := &EncryptedMessage{
+		Version:      Version,
+		SenderDevice: d.ID,
+		Envelopes:    make([]Envelope, 0, len(recipients)),
+	}
+	for _, r := range recipients {
+		_, preKey, err := r.Bundle.decode()
+		if err != nil {
+			return nil, err
+		}
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := ephemeral.ECDH(preKey)
+		if err != nil {
+			return nil, err
+		}
+		ephemeralKey := ephemeral.PublicKey().Bytes()
+		aead, err := newAEAD(secret, ephemeralKey, preKey.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+
+		ad := associatedData(d.Username, d.ID, r.Username, r.Bundle.DeviceID, ephemeralKey)
+		ciphertext := aead.Seal(nil, nonce, plaintext, ad)
+		msg.Envelopes = append(msg.Envelopes, Envelope{
+			Recipient:    r.Username,
+			DeviceID:     r.Bundle.DeviceID,
+			EphemeralKey: encoding.EncodeToString(ephemeralKey),
+			Nonce:        encoding.EncodeToString(nonce),
+			Ciphertext:   encoding.EncodeToString(ciphertext),
+			Signature:    encoding.EncodeToString(ed25519.Sign(d.identity, signedData(ad, nonce, ciphertext))),
+		})
+	}
+	return msg, nil
+}
+
+// Open decrypts an envelope sealed for this device, once its signature matches the bundle of the
+// sender's device
+func (d *Device) Open(senderUsername string, sender Bundle, e Envelope) ([]byte, error) {
+	if e.Recipient != d.Username || e.DeviceID != d.ID {
+		return nil, ErrWrongDevice
+	}
+	identity, _, err := sender.decode()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, nonce, ciphertext, signature, err := e.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	ad := associatedData(senderUsername, sender.DeviceID, d.Username, d.ID, ephemeralKey)
+	if !ed25519.Verify(identity, signedData(ad, nonce, ciphertext), signature) {
+		return nil, ErrBadSignature
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralKey)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	secret, err := d.preKey.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	aead, err := newAEAD(secret, ephemeralKey, d.preKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, nonce, ciphertext, ad)
+}
+
+// newAEAD derives the message key with HKDF-SHA256 (RFC 5869), salted with both public keys
+func newAEAD(secret, ephemeralKey, preKey []byte) (cipher.AEAD, error) {
+	extract := hmac.New(sha256.New, append(append([]byte{}, ephemeralKey...), preKey...))
+	extract.Write(secret)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(kdfInfo))
+	expand.Write([]byte{1})
+
+	block, err := aes.NewCipher(expand.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// associatedData binds the ciphertext to both users, both devices and the ephemeral key, fields are
+// length prefixed so that no two combinations encode the same
+func associatedData(sender, senderDevice, recipient, recipientDevice string, ephemeralKey []byte) []byte {
+	ad := []byte{Version}
+	for _, field := range [][]byte{[]byte(sender), []byte(senderDevice), []byte(recipient), []byte(recipientDevice), ephemeralKey} {
+		ad = binary.BigEndian.AppendUint16(ad, uint16(len(field)))
+		ad = append(ad, field...)
+	}
+	return ad
+}
+
+func signedData(ad, nonce, ciphertext []byte) []byte {
+	data := append(append([]byte{}, ad...), nonce...)
+	return append(data, ciphertext...)
+}
@@ -0,0 +1,243 @@
+package e2ee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func mustDevice(t *testing.T, username, id string) *Device {
+	t.Helper()
+	d, err := NewDevice(username, id)
+	if err != nil {
+		t.Fatalf("NewDevice(%q, %q): %v", username, id, err)
+	}
+	return d
+}
+
+func TestKeyExchange(t *testing.T) {
+	alicePhone := mustDevice(t, "alice", "alice-phone")
+	aliceLaptop := mustDevice(t, "alice", "alice-laptop")
+	bobPhone := mustDevice(t, "bob", "bob-phone")
+	bobTablet := mustDevice(t, "bob", "bob-tablet")
+
+	plaintext := []byte("the cat is back home")
+	msg, err := alicePhone.Seal(plaintext, []Recipient{
+		{Username: "bob", Bundle: bobPhone.Bundle()},
+		{Username: "bob", Bundle: bobTablet.Bundle()},
+		{Username: "alice", Bundle: aliceLaptop.Bundle()},
+	})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if err := msg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(msg.Envelopes) != 3 {
+		t.Fatalf("got %d envelopes, want 3", len(msg.Envelopes))
+	}
+
+	for _, d := range []*Device{bobPhone, bobTablet, aliceLaptop} {
+		e, ok := msg.EnvelopeFor(d.Username, d.ID)
+		if !ok {
+			t.Fatalf("no envelope for %s", d.ID)
+		}
+		got, err := d.Open("alice", alicePhone.Bundle(), e)
+		if err != nil {
+			t.Fatalf("%s Open: %v", d.ID, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("%s opened %q, want %q", d.ID, got, plaintext)
+		}
+	}
+
+	if _, ok := msg.EnvelopeFor(alicePhone.Username, alicePhone.ID); ok {
+		t.Fatal("the sending device should not get an envelope")
+	}
+}
+
+// Device IDs are only unique per user, every user here has a "phone"
+func TestCollidingDeviceIDs(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	aliceLaptop := mustDevice(t, "alice", "laptop")
+	bob := mustDevice(t, "bob", "phone")
+
+	msg, err := alice.Seal([]byte("same name"), []Recipient{
+		{Username: "bob", Bundle: bob.Bundle()},
+		{Username: "alice", Bundle: aliceLaptop.Bundle()},
+	})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if _, ok := msg.EnvelopeFor("alice", "phone"); ok {
+		t.Fatal("the sending device should not get bob's envelope")
+	}
+	e, ok := msg.EnvelopeFor("bob", "phone")
+	if !ok {
+		t.Fatal("no envelope for bob's phone")
+	}
+	got, err := bob.Open("alice", alice.Bundle(), e)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(got) != "same name" {
+		t.Fatalf("opened %q", got)
+	}
+	if _, err := alice.Open("alice", alice.Bundle(), e); !errors.Is(err, ErrWrongDevice) {
+		t.Fatalf("other user's phone: got %v, want ErrWrongDevice", err)
+	}
+
+	// Relabelling the envelope for another user with the same device ID breaks the signature
+	carol := mustDevice(t, "carol", "phone")
+	relabelled := e
+	relabelled.Recipient = "carol"
+	if _, err := carol.Open("alice", alice.Bundle(), relabelled); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("relabelled envelope: got %v, want ErrBadSignature", err)
+	}
+	// And so does claiming another sender with the same device ID
+	if _, err := bob.Open("mallory", alice.Bundle(), e); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("other sender: got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestEnvelopesDiffer(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+
+	a, err := alice.Seal([]byte("same"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	b, err := alice.Seal([]byte("same"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if a.Envelopes[0].EphemeralKey == b.Envelopes[0].EphemeralKey || a.Envelopes[0].Ciphertext == b.Envelopes[0].Ciphertext {
+		t.Fatal("sealing twice should use fresh ephemeral keys")
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+
+	msg, err := alice.Seal([]byte("hello"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	e := msg.Envelopes[0]
+
+	ciphertext, _ := encoding.DecodeString(e.Ciphertext)
+	ciphertext[0] ^= 1
+	tampered := e
+	tampered.Ciphertext = encoding.EncodeToString(ciphertext)
+	if _, err := bob.Open("alice", alice.Bundle(), tampered); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("tampered ciphertext: got %v, want ErrBadSignature", err)
+	}
+
+	// An envelope moved to another device no longer matches its signature
+	carol := mustDevice(t, "carol", "phone")
+	moved := e
+	moved.Recipient = carol.Username
+	moved.DeviceID = carol.ID
+	if _, err := carol.Open("alice", alice.Bundle(), moved); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("moved envelope: got %v, want ErrBadSignature", err)
+	}
+
+	if _, err := carol.Open("alice", alice.Bundle(), e); !errors.Is(err, ErrWrongDevice) {
+		t.Fatalf("other device: got %v, want ErrWrongDevice", err)
+	}
+}
+
+func TestOpenRejectsImpersonation(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	mallory := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+
+	msg, err := mallory.Seal([]byte("send me your password"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if _, err := bob.Open("alice", alice.Bundle(), msg.Envelopes[0]); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSealRejectsUnsignedPreKey(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+	mallory := mustDevice(t, "mallory", "phone")
+
+	// A server swapping the pre-key cannot sign it with bob's identity key
+	bundle := bob.Bundle()
+	bundle.PreKey = mallory.Bundle().PreKey
+	if err := bundle.Verify(); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Verify: got %v, want ErrBadSignature", err)
+	}
+	if _, err := alice.Seal([]byte("hi"), []Recipient{{Username: "bob", Bundle: bundle}}); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Seal: got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestDeviceRestore(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+
+	state, err := json.Marshal(bob)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var restored Device
+	if err := json.Unmarshal(state, &restored); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if restored.Bundle() != bob.Bundle() {
+		t.Fatal("restored device should publish the same bundle")
+	}
+
+	msg, err := alice.Seal([]byte("still there?"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	got, err := restored.Open("alice", alice.Bundle(), msg.Envelopes[0])
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(got) != "still there?" {
+		t.Fatalf("opened %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	alice := mustDevice(t, "alice", "phone")
+	bob := mustDevice(t, "bob", "phone")
+
+	msg, err := alice.Seal([]byte("hi"), []Recipient{{Username: "bob", Bundle: bob.Bundle()}})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(m *EncryptedMessage)
+		want   error
+	}{
+		{"version", func(m *EncryptedMessage) { m.Version = Version + 1 }, ErrUnsupportedVersion},
+		{"no envelopes", func(m *EncryptedMessage) { m.Envelopes = nil }, ErrInvalidEnvelope},
+		{"no sender device", func(m *EncryptedMessage) { m.SenderDevice = "" }, ErrInvalidEnvelope},
+		{"duplicate device", func(m *EncryptedMessage) { m.Envelopes = append(m.Envelopes, m.Envelopes[0]) }, ErrInvalidEnvelope},
+		{"short nonce", func(m *EncryptedMessage) { m.Envelopes[0].Nonce = encoding.EncodeToString([]byte("short")) }, ErrInvalidEnvelope},
+		{"not base64", func(m *EncryptedMessage) { m.Envelopes[0].Ciphertext = "%%%" }, ErrInvalidEnvelope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := *msg
+			m.Envelopes = append([]Envelope{}, msg.Envelopes...)
+			tt.mutate(&m)
+			if err := m.Validate(); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package entity
+
+type AttachmentType string
+
+const (
+	AttachmentImage    AttachmentType = "image"
+	AttachmentVideo    AttachmentType = "video"
+	AttachmentFile     AttachmentType = "file"
+	AttachmentLocation AttachmentType = "location"
+	AttachmentPost     AttachmentType = "post"
+)
+
+type Attachment struct {
+	ID        int `gorm:"primaryKey;autoIncrement"`
+	MessageID int `gorm:"index"`
+	Type      AttachmentType
+	URL       string `gorm:"size:2048"`
+	Name      string
+	MimeType  string
+	Size      int64
+
+	// Location
+	Latitude  *float64
+	Longitude *float64
+
+	// Shared post
+	PostID string
+}
+
+type LinkPreview struct {
+	ID          int    `gorm:"primaryKey;autoIncrement"`
+	MessageID   int    `gorm:"uniqueIndex"`
+	URL         string `gorm:"size:2048"`
+	Title       string
+	Description string `gorm:"size:1024"`
+	Image       string `gorm:"size:2048"`
+}
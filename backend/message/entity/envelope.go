@@ -0,0 +1,23 @@
+package entity
+
+// MessageProtocol tells how a message's content is to be read
+type MessageProtocol string
+
+const (
+	ProtocolPlain MessageProtocol = "plain"
+	// ProtocolE2EE messages leave Content empty, their ciphertext is in one envelope per recipient device
+	ProtocolE2EE MessageProtocol = "e2ee"
+)
+
+// MessageEnvelope is an end-to-end encrypted message sealed for a single device. Every field is
+// opaque to the server, see the e2ee package for how clients produce and open them
+type MessageEnvelope struct {
+	ID           int `gorm:"primaryKey;autoIncrement"`
+	MessageID    int `gorm:"index"`
+	Recipient    string
+	DeviceID     string `gorm:"size:64"`
+	EphemeralKey string
+	Nonce        string
+	Ciphertext   string `gorm:"type:text"`
+	Signature    string
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+type ExportFormat string
+
+const (
+	ExportJSON ExportFormat = "json"
+	ExportHTML ExportFormat = "html"
+	ExportText ExportFormat = "txt"
+)
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// ChatExport is a chat history generated in the background for a single member, the file is written
+// to Path and kept until ExpiresAt
+type ChatExport struct {
+	ID          string `gorm:"primaryKey;size:32"`
+	GroupID     int    `gorm:"index"`
+	Username    string `gorm:"index"`
+	Format      ExportFormat
+	Status      ExportStatus `gorm:"default:pending"`
+	Error       string
+	Path        string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
@@ -1,8 +1,35 @@
 package entity
 
+import "time"
+
+// RetentionPolicy decides how long the messages of a group are kept before the sweeper deletes them
+type RetentionPolicy string
+
+const (
+	RetentionForever RetentionPolicy = "forever"
+	// RetentionDays keeps messages for Group.RetentionDays days after they were sent
+	RetentionDays RetentionPolicy = "days"
+	// RetentionRead deletes a message once every member has read it
+	RetentionRead RetentionPolicy = "read"
+)
+
 type Group struct {
-	ID        int `gorm:"primaryKey;autoIncrement"`
-	Name      string
-	IsDirect  bool
-	OwnerName string
+	ID          int `gorm:"primaryKey;autoIncrement"`
+	Name        string
+	IsDirect    bool
+	OwnerName   string
+	Avatar      string
+	Description string `gorm:"size:512"`
+	CreatedAt   time.Time
+
+	// RequiresApproval queues users joining through an invite until an admin accepts them
+	RequiresApproval   bool `gorm:"default:false"`
+	OnlyAdminsPost     bool `gorm:"default:false"`
+	OnlyAdminsEditInfo bool `gorm:"default:false"`
+
+	// Encrypted direct groups only accept end-to-end encrypted messages
+	Encrypted bool `gorm:"default:false"`
+
+	RetentionPolicy RetentionPolicy `gorm:"default:forever"`
+	RetentionDays   int             `gorm:"default:0"`
 }
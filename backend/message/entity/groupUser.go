@@ -1,6 +1,28 @@
 package entity
 
+import "time"
+
+type GroupRole string
+
+const (
+	GroupRoleOwner  GroupRole = "owner"
+	GroupRoleAdmin  GroupRole = "admin"
+	GroupRoleMember GroupRole = "member"
+)
+
 type GroupUser struct {
-	Username string `gorm:"primaryKey"`
-	GroupID  int    `gorm:"primaryKey"`
+	Username          string    `gorm:"primaryKey"`
+	GroupID           int       `gorm:"primaryKey"`
+	Role              GroupRole `gorm:"size:16;default:member"`
+	LastReadMessageID int       `gorm:"default:0"`
+	JoinedAt          time.Time `gorm:"autoCreateTime;default:now()"`
+
+	// Preferences of the member, they only affect how the group shows up for them
+	MutedUntil *time.Time
+	Archived   bool `gorm:"default:false"`
+	Pinned     bool `gorm:"default:false"`
+}
+
+func (gu *GroupUser) IsMuted() bool {
+	return gu.MutedUntil != nil && gu.MutedUntil.After(time.Now())
 }
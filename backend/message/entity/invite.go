@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// GroupInvite is a shareable code that lets users join a group on their own
+type GroupInvite struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	Code      string `gorm:"size:32;uniqueIndex"`
+	GroupID   int    `gorm:"index"`
+	CreatedBy string
+	ExpiresAt *time.Time
+	MaxUses   int `gorm:"default:0"` // 0 means unlimited
+	Uses      int `gorm:"default:0"`
+	CreatedAt time.Time
+}
+
+type JoinRequest struct {
+	GroupID    int    `gorm:"primaryKey"`
+	Username   string `gorm:"primaryKey"`
+	InviteCode string `gorm:"size:32"`
+	CreatedAt  time.Time
+}
@@ -3,10 +3,48 @@ package entity
 import "time"
 
 type Message struct {
-	ID        int `gorm:"primaryKey;autoIncrement"`
+	ID        int `gorm:"primaryKey;autoIncrement;index:idx_messages_group_created,priority:3"`
 	Username  string
-	GroupID   int
-	Group     Group  `gorm:"foreignKey:GroupID"`
+	GroupID   int       `gorm:"index:idx_messages_group_created,priority:1"`
+	Group     Group     `gorm:"foreignKey:GroupID"`
+	Content   string    `gorm:"size:1024"`
+	CreatedAt time.Time `gorm:"index:idx_messages_group_created,priority:2"`
+	EditedAt  *time.Time
+	DeletedAt *time.Time
+
+	// System messages record group events, their content is a "group:<event>[:<value>]" key
+	System bool `gorm:"default:false"`
+
+	Protocol        MessageProtocol `gorm:"default:plain"`
+	ProtocolVersion int             `gorm:"default:0"`
+	SenderDevice    string
+
+	ReplyToMessageID *int
+	ReplyTo          *Message `gorm:"foreignKey:ReplyToMessageID"`
+
+	Attachments []*Attachment      `gorm:"foreignKey:MessageID"`
+	LinkPreview *LinkPreview       `gorm:"foreignKey:MessageID"`
+	Reactions   []*MessageReaction `gorm:"foreignKey:MessageID"`
+	Envelopes   []*MessageEnvelope `gorm:"foreignKey:MessageID"`
+}
+
+// MessageEdit keeps the content a message had before an edit
+type MessageEdit struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	MessageID int    `gorm:"index"`
 	Content   string `gorm:"size:1024"`
-	CreatedAt time.Time
+	EditedAt  time.Time
+}
+
+// MessageSearchResult pairs a matched message with a snippet of its content, where matches are
+// wrapped in HighlightStart and HighlightStop
+type MessageSearchResult struct {
+	Message *Message
+	Snippet string
 }
+
+// Control characters do not show up in typed chat content, which makes them safe highlight delimiters
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// MessageReaction holds at most one emoji reaction per user on a message
+type MessageReaction struct {
+	MessageID int    `gorm:"primaryKey"`
+	Username  string `gorm:"primaryKey"`
+	Type      string `gorm:"size:32"`
+	CreatedAt time.Time
+}
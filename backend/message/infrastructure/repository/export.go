@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"message/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	db.AutoMigrate(&entity.ChatExport{})
+	return &ExportRepository{db: db}
+}
+
+func (r *ExportRepository) CreateExport(export *entity.ChatExport) (*entity.ChatExport, error) {
+	err := r.db.Create(export).Error
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *ExportRepository) GetExport(exportID string) (*entity.ChatExport, error) {
+	var export entity.ChatExport
+	err := r.db.Where("id = ? AND expires_at > ?", exportID, time.Now()).Take(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindPendingExport finds an export of the same history still being generated, so asking twice
+// does not start a second job
+func (r *ExportRepository) FindPendingExport(groupID int, username string, format entity.ExportFormat) (*entity.ChatExport, error) {
+	var export entity.ChatExport
+	err := r.db.
+		Where("group_id = ? AND username = ? AND format = ? AND status = ? AND expires_at > ?", groupID, username, format, entity.ExportPending, time.Now()).
+		Take(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// CompleteExport only completes an export that is still pending, and reports false when it was
+// failed in the meantime
+func (r *ExportRepository) CompleteExport(exportID string, path string) (bool, error) {
+	result := r.db.Model(&entity.ChatExport{}).Where("id = ? AND status = ?", exportID, entity.ExportPending).Updates(map[string]interface{}{
+		"status":       entity.ExportReady,
+		"path":         path,
+		"completed_at": time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *ExportRepository) FailExport(exportID string, reason string) error {
+	return r.db.Model(&entity.ChatExport{}).Where("id = ?", exportID).Updates(map[string]interface{}{
+		"status":       entity.ExportFailed,
+		"error":        reason,
+		"completed_at": time.Now(),
+	}).Error
+}
+
+// FailStaleExports fails the exports still pending since before the given time, whose job died
+// with a restart of the service or is stuck
+func (r *ExportRepository) FailStaleExports(before time.Time) error {
+	return r.db.Model(&entity.ChatExport{}).Where("status = ? AND created_at < ?", entity.ExportPending, before).Updates(map[string]interface{}{
+		"status":       entity.ExportFailed,
+		"error":        "export timed out",
+		"completed_at": time.Now(),
+	}).Error
+}
+
+// DeleteExpiredExports returns the deleted exports so that their files can be removed
+func (r *ExportRepository) DeleteExpiredExports() ([]*entity.ChatExport, error) {
+	var expired []*entity.ChatExport
+	if err := r.db.Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(expired))
+	for i, export := range expired {
+		ids[i] = export.ID
+	}
+	if err := r.db.Where("id IN ?", ids).Delete(&entity.ChatExport{}).Error; err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
@@ -7,6 +7,7 @@ import (
 	"message/util"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GroupUserRepository struct {
@@ -15,9 +16,85 @@ type GroupUserRepository struct {
 
 func NewGroupUserRepository(db *gorm.DB) *GroupUserRepository {
 	db.AutoMigrate(&entity.GroupUser{})
+
+	// Memberships created before roles existed only know their owner through groups.owner_name
+	db.Exec("UPDATE group_users SET role = ? FROM groups WHERE groups.id = group_users.group_id AND groups.owner_name = group_users.username AND group_users.role <> ?", entity.GroupRoleOwner, entity.GroupRoleOwner)
+
 	return &GroupUserRepository{db: db}
 }
 
+func (r *GroupUserRepository) GetRole(groupID int, username string) (entity.GroupRole, error) {
+	var groupUser entity.GroupUser
+	err := r.db.Where("group_id = ? AND username = ?", groupID, username).Take(&groupUser).Error
+	if err != nil {
+		return "", err
+	}
+	return groupUser.Role, nil
+}
+
+func (r *GroupUserRepository) GetRoles(groupID int) (map[string]entity.GroupRole, error) {
+	var groupUsers []*entity.GroupUser
+	err := r.db.Where("group_id = ?", groupID).Find(&groupUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]entity.GroupRole, len(groupUsers))
+	for _, gu := range groupUsers {
+		roles[gu.Username] = gu.Role
+	}
+	return roles, nil
+}
+
+func (r *GroupUserRepository) UpdateRole(groupID int, username string, role entity.GroupRole) error {
+	err := r.db.
+		Model(&entity.GroupUser{}).
+		Where("group_id = ? AND username = ?", groupID, username).
+		Update("role", role).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TransferOwnership demotes the current owner to admin and keeps groups.owner_name in step
+func (r *GroupUserRepository) TransferOwnership(groupID int, from string, to string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&entity.GroupUser{}).
+			Where("group_id = ? AND username = ?", groupID, from).
+			Update("role", entity.GroupRoleAdmin).Error
+		if err != nil {
+			return err
+		}
+		err = tx.
+			Model(&entity.GroupUser{}).
+			Where("group_id = ? AND username = ?", groupID, to).
+			Update("role", entity.GroupRoleOwner).Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(&entity.Group{}).
+			Where("id = ?", groupID).
+			Update("owner_name", to).Error
+	})
+}
+
+// GetSuccessor picks who inherits a group from a leaving owner: the longest standing admin,
+// or the longest standing member when there is no admin
+func (r *GroupUserRepository) GetSuccessor(groupID int, leaving string) (string, error) {
+	var groupUser entity.GroupUser
+	err := r.db.
+		Where("group_id = ? AND username <> ?", groupID, leaving).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "role = ? DESC, joined_at ASC, username ASC", Vars: []interface{}{entity.GroupRoleAdmin}}}).
+		Take(&groupUser).Error
+	if err != nil {
+		return "", err
+	}
+	return groupUser.Username, nil
+}
+
 func (r *GroupUserRepository) CheckUserInGroup(groupID int, username string) (bool, error) {
 	var count int64
 	err := r.db.Model(&entity.GroupUser{}).Where("group_id = ? AND username = ?", groupID, username).Count(&count).Error
@@ -39,16 +116,54 @@ func (r *GroupUserRepository) GetUsersInGroup(groupID int) ([]string, error) {
 	return users, nil
 }
 
-func (r *GroupUserRepository) GetGroupsOfUser(username string, pagination util.Pagination) ([]*entity.Group, error) {
+func (r *GroupUserRepository) GetGroupIDsOfUser(username string) ([]int, error) {
+	var groupIDs []int
+	err := r.db.
+		Model(&entity.GroupUser{}).
+		Where("username = ?", username).
+		Pluck("group_id", &groupIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return groupIDs, nil
+}
+
+func (r *GroupUserRepository) GetLastReadMessageID(groupID int, username string) (int, error) {
+	var groupUser entity.GroupUser
+	err := r.db.Where("group_id = ? AND username = ?", groupID, username).Take(&groupUser).Error
+	if err != nil {
+		return 0, err
+	}
+	return groupUser.LastReadMessageID, nil
+}
+
+// UpdateLastReadMessageID only moves the read marker forward
+func (r *GroupUserRepository) UpdateLastReadMessageID(groupID int, username string, messageID int) error {
+	err := r.db.
+		Model(&entity.GroupUser{}).
+		Where("group_id = ? AND username = ? AND last_read_message_id < ?", groupID, username, messageID).
+		Update("last_read_message_id", messageID).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetGroupsOfUser lists pinned groups first, then the rest by their latest activity. Groups created
+// before created_at was tracked have no activity time at all and go last
+func (r *GroupUserRepository) GetGroupsOfUser(username string, archived bool, pagination util.Pagination) ([]*entity.Group, error) {
 	var groups []*entity.Group
 	err := r.db.
 		Model(&entity.Group{}).
 		Joins("join group_users gu on groups.id = gu.group_id").
-		Where("username = ?", username).
+		Where("gu.username = ? AND gu.archived = ?", username, archived).
 		Select("groups.*").
-		Find(&groups).
+		Order("gu.pinned desc").
+		Order("coalesce((select max(m.created_at) from messages m where m.group_id = groups.id), groups.created_at) desc nulls last").
+		Order("groups.id desc").
 		Offset(pagination.Offset()).
 		Limit(pagination.Size).
+		Find(&groups).
 		Error
 	if err != nil {
 		return nil, err
@@ -56,34 +171,74 @@ func (r *GroupUserRepository) GetGroupsOfUser(username string, pagination util.P
 	return groups, nil
 }
 
-func (r *GroupUserRepository) GetDirectGroup(userA string, userB string) (*entity.Group, error) {
-	fmt.Println(userA + " " + userB)
-	group := entity.Group{
-		IsDirect: true,
+func (r *GroupUserRepository) GetMembership(groupID int, username string) (*entity.GroupUser, error) {
+	var groupUser entity.GroupUser
+	err := r.db.Where("group_id = ? AND username = ?", groupID, username).Take(&groupUser).Error
+	if err != nil {
+		return nil, err
 	}
+	return &groupUser, nil
+}
 
+func (r *GroupUserRepository) UpdatePreferences(groupID int, username string, preferences map[string]interface{}) error {
+	err := r.db.
+		Model(&entity.GroupUser{}).
+		Where("group_id = ? AND username = ?", groupID, username).
+		Updates(preferences).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *GroupUserRepository) GetMutedUsers(groupID int) ([]string, error) {
+	var users []string
+	err := r.db.
+		Model(&entity.GroupUser{}).
+		Where("group_id = ? AND muted_until > now()", groupID).
+		Pluck("username", &users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindDirectGroup is GetDirectGroup without creating the group when there is none yet
+func (r *GroupUserRepository) FindDirectGroup(userA string, userB string) (*entity.Group, error) {
+	var group entity.Group
 	err := r.db.
 		Model(&entity.Group{}).
 		Joins("join group_users gu1 on gu1.group_id = groups.id").
 		Joins("join group_users gu2 on gu2.group_id = groups.id").
 		Where("groups.is_direct = true AND gu1.username = ? AND gu2.username = ?", userA, userB).
 		Take(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
 
+func (r *GroupUserRepository) GetDirectGroup(userA string, userB string) (*entity.Group, error) {
+	fmt.Println(userA + " " + userB)
+	group, err := r.FindDirectGroup(userA, userB)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		if r.db.Create(&group).Error != nil ||
-			r.AddUserToGroup(group.ID, userA) != nil ||
-			r.AddUserToGroup(group.ID, userB) != nil {
+		group = &entity.Group{
+			IsDirect: true,
+		}
+		if r.db.Create(group).Error != nil ||
+			r.AddUserToGroup(group.ID, userA, entity.GroupRoleMember) != nil ||
+			r.AddUserToGroup(group.ID, userB, entity.GroupRoleMember) != nil {
 			return nil, err
 		}
 	} else if err != nil {
 		return nil, err
 	}
 
-	return &group, nil
+	return group, nil
 }
 
-func (r *GroupUserRepository) AddUserToGroup(groupId int, username string) error {
-	err := r.db.Create(&entity.GroupUser{GroupID: groupId, Username: username}).Error
+func (r *GroupUserRepository) AddUserToGroup(groupId int, username string, role entity.GroupRole) error {
+	err := r.db.Create(&entity.GroupUser{GroupID: groupId, Username: username, Role: role}).Error
 	if err != nil {
 		return err
 	}
@@ -113,3 +268,16 @@ func (r *GroupUserRepository) RemoveUserMemberships(username string) error {
 	}
 	return nil
 }
+
+// GetMembersOfGroups loads the memberships of several groups at once, in joining order
+func (r *GroupUserRepository) GetMembersOfGroups(groupIDs []int) ([]*entity.GroupUser, error) {
+	var groupUsers []*entity.GroupUser
+	err := r.db.
+		Where("group_id IN ?", groupIDs).
+		Order("group_id, joined_at, username").
+		Find(&groupUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	return groupUsers, nil
+}
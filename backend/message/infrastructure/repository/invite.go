@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"message/entity"
+
+	"gorm.io/gorm"
+)
+
+type InviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) *InviteRepository {
+	db.AutoMigrate(&entity.GroupInvite{}, &entity.JoinRequest{})
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) CreateInvite(invite *entity.GroupInvite) (*entity.GroupInvite, error) {
+	err := r.db.Create(invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (r *InviteRepository) GetInvite(code string) (*entity.GroupInvite, error) {
+	var invite entity.GroupInvite
+	err := r.db.Where("code = ?", code).Take(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) GetInvitesOfGroup(groupID int) ([]*entity.GroupInvite, error) {
+	var invites []*entity.GroupInvite
+	err := r.db.Where("group_id = ?", groupID).Order("created_at desc").Find(&invites).Error
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *InviteRepository) DeleteInvite(groupID int, code string) error {
+	err := r.db.Where("group_id = ? AND code = ?", groupID, code).Delete(&entity.GroupInvite{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UseInvite counts one use of the invite and reports false when it has none left
+func (r *InviteRepository) UseInvite(inviteID int) (bool, error) {
+	result := r.db.
+		Model(&entity.GroupInvite{}).
+		Where("id = ? AND (max_uses = 0 OR uses < max_uses)", inviteID).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *InviteRepository) DeleteInvitesOfGroup(groupID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("group_id = ?", groupID).Delete(&entity.GroupInvite{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("group_id = ?", groupID).Delete(&entity.JoinRequest{}).Error
+	})
+}
+
+func (r *InviteRepository) CreateJoinRequest(request *entity.JoinRequest) (*entity.JoinRequest, error) {
+	err := r.db.Create(request).Error
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *InviteRepository) GetJoinRequest(groupID int, username string) (*entity.JoinRequest, error) {
+	var request entity.JoinRequest
+	err := r.db.Where("group_id = ? AND username = ?", groupID, username).Take(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *InviteRepository) GetJoinRequests(groupID int) ([]*entity.JoinRequest, error) {
+	var requests []*entity.JoinRequest
+	err := r.db.Where("group_id = ?", groupID).Order("created_at asc").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *InviteRepository) DeleteJoinRequest(groupID int, username string) error {
+	err := r.db.Where("group_id = ? AND username = ?", groupID, username).Delete(&entity.JoinRequest{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
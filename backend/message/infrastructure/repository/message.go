@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"fmt"
 	"message/entity"
 	"message/util"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MessageRepository struct {
@@ -12,7 +15,12 @@ type MessageRepository struct {
 }
 
 func NewMessageRepository(db *gorm.DB) *MessageRepository {
-	db.AutoMigrate(&entity.Message{})
+	db.AutoMigrate(&entity.Message{}, &entity.MessageEdit{}, &entity.Attachment{}, &entity.LinkPreview{}, &entity.MessageReaction{}, &entity.MessageEnvelope{})
+
+	// gorm cannot declare generated columns, the search vector is kept by postgres itself
+	db.Exec("ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector)")
+
 	return &MessageRepository{db: db}
 }
 
@@ -32,16 +40,34 @@ func (r *MessageRepository) DeleteMessagesByUser(username string) error {
 	return nil
 }
 
-func (r *MessageRepository) GetGroupMessageList(groupID int, pagination util.Pagination) ([]*entity.Message, error) {
-	var messages []*entity.Message
-	err := r.db.
+// GetGroupMessageList walks the (created_at, id) index away from the cursor, so rows come back
+// newest-first for Before and oldest-first for After
+func (r *MessageRepository) GetGroupMessageList(groupID int, pagination util.CursorPagination, limit int) ([]*entity.Message, error) {
+	query := r.db.
 		Model(&entity.Message{}).
-		Joins("join groups g on g.id = messages.group_id").
-		Where("group_id = ?", groupID).
-		Find(&messages).
-		Offset(pagination.Offset()).
-		Limit(pagination.Size).
-		Error
+		Where("group_id = ?", groupID)
+
+	if pagination.Direction == util.After {
+		if pagination.Cursor != nil {
+			query = query.Where("(created_at, id) > (?, ?)", pagination.Cursor.CreatedAt, pagination.Cursor.ID)
+		}
+		query = query.Order("created_at asc, id asc")
+	} else {
+		if pagination.Cursor != nil {
+			query = query.Where("(created_at, id) < (?, ?)", pagination.Cursor.CreatedAt, pagination.Cursor.ID)
+		}
+		query = query.Order("created_at desc, id desc")
+	}
+
+	var messages []*entity.Message
+	err := query.
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("Envelopes").
+		Preload("ReplyTo").
+		Limit(limit).
+		Find(&messages).Error
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +83,11 @@ func (r *MessageRepository) GetDirectMessageList(userA string, userB string, pag
 		Joins("join group_users ug2 on ug2.username = ? and ug2.group_id = ug1.group_id", userB).
 		Joins("join groups on groups.id = messages.group_id").
 		Where("groups.is_direct = ?", true).
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("Envelopes").
+		Preload("ReplyTo").
 		Offset(pagination.Offset()).
 		Limit(pagination.Size).
 		Find(&messages).
@@ -68,16 +99,321 @@ func (r *MessageRepository) GetDirectMessageList(userA string, userB string, pag
 	return messages, nil
 }
 
+func (r *MessageRepository) GetMessage(messageID int) (*entity.Message, error) {
+	var message entity.Message
+	err := r.db.
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("Envelopes").
+		Preload("ReplyTo").
+		First(&message, messageID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// UpdateMessageContent stores the previous content as an edit before overwriting it
+func (r *MessageRepository) UpdateMessageContent(message *entity.Message, content string) (*entity.Message, error) {
+	editedAt := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&entity.MessageEdit{
+			MessageID: message.ID,
+			Content:   message.Content,
+			EditedAt:  editedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(message).
+			Omit(clause.Associations).
+			Updates(map[string]interface{}{"content": content, "edited_at": editedAt}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	message.Content = content
+	message.EditedAt = &editedAt
+	return message, nil
+}
+
+// ReplaceLinkPreview only touches messages that still hold content and are not deleted, so a preview
+// fetched for content that was edited away in the meantime is dropped. It reports whether it replaced
+func (r *MessageRepository) ReplaceLinkPreview(messageID int, content string, preview *entity.LinkPreview) (bool, error) {
+	replaced := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var message entity.Message
+		result := tx.
+			Select("id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND content = ? AND deleted_at IS NULL", messageID, content).
+			Limit(1).
+			Find(&message)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		err := tx.Where("message_id = ?", messageID).Delete(&entity.LinkPreview{}).Error
+		if err != nil {
+			return err
+		}
+		replaced = true
+		if preview == nil {
+			return nil
+		}
+		preview.MessageID = messageID
+		return tx.Create(preview).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return replaced, nil
+}
+
+// DeleteMessage keeps the row as a tombstone but drops its content, attachments, reactions, envelopes and edit history
+func (r *MessageRepository) DeleteMessage(message *entity.Message) (*entity.Message, error) {
+	deletedAt := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&entity.MessageEdit{}, &entity.Attachment{}, &entity.LinkPreview{}, &entity.MessageReaction{}, &entity.MessageEnvelope{}} {
+			if err := tx.Where("message_id = ?", message.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.
+			Model(message).
+			Omit(clause.Associations).
+			Updates(map[string]interface{}{"content": "", "deleted_at": deletedAt}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	message.Content = ""
+	message.DeletedAt = &deletedAt
+	message.Attachments = nil
+	message.LinkPreview = nil
+	message.Reactions = nil
+	message.Envelopes = nil
+	return message, nil
+}
+
+// DeleteExpiredMessages hard deletes up to limit messages that outlived the retention policy of their
+// group, together with everything attached to them. Replies to them lose their reference
+func (r *MessageRepository) DeleteExpiredMessages(limit int) ([]*entity.Message, error) {
+	var expired []*entity.Message
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`
+			SELECT m.id, m.group_id FROM messages m
+			JOIN groups g ON g.id = m.group_id
+			WHERE (g.retention_policy = ? AND m.created_at < now() - make_interval(days => g.retention_days))
+				OR (g.retention_policy = ? AND NOT EXISTS (
+					SELECT 1 FROM group_users gu WHERE gu.group_id = m.group_id AND gu.last_read_message_id < m.id
+				))
+			ORDER BY m.id
+			LIMIT ?
+			FOR UPDATE OF m SKIP LOCKED`,
+			entity.RetentionDays, entity.RetentionRead, limit).
+			Scan(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]int, len(expired))
+		for i, m := range expired {
+			ids[i] = m.ID
+		}
+		err = tx.
+			Model(&entity.Message{}).
+			Where("reply_to_message_id IN ?", ids).
+			Update("reply_to_message_id", nil).Error
+		if err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&entity.MessageEdit{}, &entity.Attachment{}, &entity.LinkPreview{}, &entity.MessageReaction{}, &entity.MessageEnvelope{}} {
+			if err := tx.Where("message_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id IN ?", ids).Delete(&entity.Message{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+func (r *MessageRepository) GetMessageEdits(messageID int) ([]*entity.MessageEdit, error) {
+	var edits []*entity.MessageEdit
+	err := r.db.
+		Where("message_id = ?", messageID).
+		Order("edited_at desc").
+		Find(&edits).Error
+	if err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+func (r *MessageRepository) UpsertReaction(messageID int, username string, reactionType string) error {
+	err := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "message_id"}, {Name: "username"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "created_at"}),
+		}).
+		Create(&entity.MessageReaction{
+			MessageID: messageID,
+			Username:  username,
+			Type:      reactionType,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *MessageRepository) DeleteReaction(messageID int, username string) error {
+	err := r.db.Where("message_id = ? AND username = ?", messageID, username).Delete(&entity.MessageReaction{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *MessageRepository) GetLastMessage(groupID int) (*entity.Message, error) {
 	var message entity.Message
 	err := r.db.
 		Model(&entity.Message{}).
 		Where("group_id = ?", groupID).
-		// Order("created_at desc").
-		Last(&message).
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("ReplyTo").
+		Order("created_at desc, id desc").
+		Take(&message).
 		Error
 	if err != nil {
 		return nil, err
 	}
 	return &message, nil
 }
+
+// SearchMessages matches content against a web search style query in the given groups, newest first
+func (r *MessageRepository) SearchMessages(query string, groupIDs []int, cursor *util.Cursor, limit int) ([]*entity.MessageSearchResult, error) {
+	if len(groupIDs) == 0 {
+		return []*entity.MessageSearchResult{}, nil
+	}
+
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=20, MinWords=8", entity.HighlightStart, entity.HighlightStop)
+	tx := r.db.
+		Table("messages, websearch_to_tsquery('simple', ?) query", query).
+		Select("messages.id, ts_headline('simple', messages.content, query, ?) AS snippet", headlineOptions).
+		Where("messages.search_vector @@ query").
+		Where("messages.deleted_at IS NULL").
+		Where("messages.group_id IN ?", groupIDs)
+	if cursor != nil {
+		tx = tx.Where("(messages.created_at, messages.id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var hits []struct {
+		ID      int
+		Snippet string
+	}
+	err := tx.
+		Order("messages.created_at desc, messages.id desc").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []*entity.MessageSearchResult{}, nil
+	}
+
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var messages []*entity.Message
+	err = r.db.
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("ReplyTo").
+		Where("id IN ?", ids).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*entity.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	results := make([]*entity.MessageSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if msg, ok := byID[hit.ID]; ok {
+			results = append(results, &entity.MessageSearchResult{Message: msg, Snippet: hit.Snippet})
+		}
+	}
+	return results, nil
+}
+
+// GetLastMessages picks the latest message of every group with DISTINCT ON, which walks idx_messages_group_created
+func (r *MessageRepository) GetLastMessages(groupIDs []int) ([]*entity.Message, error) {
+	latest := r.db.
+		Model(&entity.Message{}).
+		Select("DISTINCT ON (group_id) id").
+		Where("group_id IN ?", groupIDs).
+		Order("group_id, created_at desc, id desc")
+
+	var messages []*entity.Message
+	err := r.db.
+		Where("id IN (?)", latest).
+		Preload("Attachments").
+		Preload("LinkPreview").
+		Preload("Reactions").
+		Preload("ReplyTo").
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) CountGroupMessages(groupID int) (int, error) {
+	var count int64
+	err := r.db.Model(&entity.Message{}).Where("group_id = ?", groupID).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// CountUnreadMessagesOfGroups counts, per group, the messages of others after the user's read marker
+func (r *MessageRepository) CountUnreadMessagesOfGroups(username string, groupIDs []int) (map[int]int, error) {
+	var rows []struct {
+		GroupID int
+		Count   int
+	}
+	err := r.db.
+		Model(&entity.Message{}).
+		Select("messages.group_id, count(*) AS count").
+		Joins("join group_users gu on gu.group_id = messages.group_id and gu.username = ?", username).
+		Where("messages.group_id IN ?", groupIDs).
+		Where("messages.id > gu.last_read_message_id AND messages.username <> ? AND messages.deleted_at IS NULL", username).
+		Group("messages.group_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.GroupID] = row.Count
+	}
+	return counts, nil
+}
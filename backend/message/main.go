@@ -1,12 +1,17 @@
 package main
 
 import (
+	"fmt"
 	"message/api/client"
 	"message/api/handler/group"
 	"message/api/handler/message"
 	repository "message/infrastructure/repository"
+	exportService "message/usecase/export"
 	groupService "message/usecase/group"
 	messageService "message/usecase/message"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -34,15 +39,34 @@ func makeHandler() *gin.Engine {
 	messageRepo := repository.NewMessageRepository(db)
 	groupRepo := repository.NewGroupRepository(db)
 	groupUserRepo := repository.NewGroupUserRepository(db)
+	inviteRepo := repository.NewInviteRepository(db)
+	exportRepo := repository.NewExportRepository(db)
 
-	messageService := messageService.NewService(messageRepo, groupUserRepo)
-	groupService := groupService.NewService(groupRepo, groupUserRepo)
+	linkPreviewClient := client.NewLinkPreviewClient()
+	if os.Getenv("LINK_PREVIEW_STUB") != "" {
+		linkPreviewClient = client.NewStubLinkPreviewClient()
+	}
 
-	wsClient := client.NewWsClient("http://gateway:8080")
+	wsClient := client.NewWsClient("http://gateway:8081")
 	userClient := client.NewUserClient("http://user:8080")
+	notiClient := client.NewNotiClient("http://noti:8080")
+
+	messageService := messageService.NewService(messageRepo, groupRepo, groupUserRepo, linkPreviewClient, userClient)
+	groupService := groupService.NewService(groupRepo, groupUserRepo, inviteRepo, notiClient, userClient)
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "chat-exports")
+	}
+	exportService := exportService.NewService(messageRepo, groupRepo, exportRepo, userClient, exportDir)
+
+	go messageService.RunRetentionSweeper(time.Minute, func(groupID int, messageIDs []int) {
+		if err := wsClient.SendExpired(groupID, messageIDs); err != nil {
+			fmt.Println("Failed to send expired messages:", err)
+		}
+	})
 
-	message.MakeHandler(app, messageService, groupService, wsClient)
-	group.MakeHandler(app, groupService, messageService, userClient)
+	message.MakeHandler(app, messageService, groupService, exportService, wsClient)
+	group.MakeHandler(app, groupService, messageService, userClient, wsClient)
 
 	return app
 }
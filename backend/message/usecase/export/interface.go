@@ -0,0 +1,17 @@
+package export
+
+import (
+	"io"
+	"message/entity"
+)
+
+type UseCase interface {
+	// IsLarge tells whether a history is big enough to be generated in the background
+	IsLarge(groupID int) (bool, error)
+	Write(w io.Writer, groupID int, format entity.ExportFormat) error
+
+	StartExport(username string, groupID int, format entity.ExportFormat) (*entity.ChatExport, error)
+	GetExport(username string, groupID int, exportID string) (*entity.ChatExport, error)
+	// GetExportFile fails with ErrExportNotReady until the export's file is written
+	GetExportFile(username string, groupID int, exportID string) (*entity.ChatExport, error)
+}
@@ -0,0 +1,261 @@
+package export
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"message/api/client"
+	"message/entity"
+	"message/infrastructure/repository"
+	"message/util"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Histories with more messages than this are generated in the background
+	largeHistory = 2000
+	batchSize    = 500
+	exportTTL    = 24 * time.Hour
+	// Exports still pending after this are taken as dead and failed
+	exportTimeout = 30 * time.Minute
+)
+
+var (
+	ErrInvalidFormat  = errors.New("format must be json, html or txt")
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportNotReady = errors.New("export is not ready yet")
+)
+
+type Service struct {
+	messageRepo *repository.MessageRepository
+	groupRepo   *repository.GroupRepository
+	exportRepo  *repository.ExportRepository
+	userClient  client.UserClient
+	// Background exports are written here and downloaded by whichever replica gets the request, so
+	// deployments mount the same shared volume on every replica, see k8s/message.yaml
+	exportDir string
+}
+
+func NewService(messageRepo *repository.MessageRepository, groupRepo *repository.GroupRepository, exportRepo *repository.ExportRepository, userClient client.UserClient, exportDir string) *Service {
+	return &Service{
+		messageRepo: messageRepo,
+		groupRepo:   groupRepo,
+		exportRepo:  exportRepo,
+		userClient:  userClient,
+		exportDir:   exportDir,
+	}
+}
+
+func ParseFormat(format string) (entity.ExportFormat, error) {
+	switch f := entity.ExportFormat(format); f {
+	case entity.ExportJSON, entity.ExportHTML, entity.ExportText:
+		return f, nil
+	case "":
+		return entity.ExportJSON, nil
+	}
+	return "", ErrInvalidFormat
+}
+
+func (s *Service) IsLarge(groupID int) (bool, error) {
+	count, err := s.messageRepo.CountGroupMessages(groupID)
+	if err != nil {
+		return false, err
+	}
+	return count > largeHistory, nil
+}
+
+// Write renders the whole history of the group oldest-first, reading it in batches so that only
+// one batch is held in memory at a time
+func (s *Service) Write(w io.Writer, groupID int, format entity.ExportFormat) error {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return err
+	}
+
+	out := newWriter(w, format)
+	if err := out.begin(group); err != nil {
+		return err
+	}
+
+	authors := make(map[string]string)
+	pagination := util.CursorPagination{Direction: util.After}
+	for {
+		messages, err := s.messageRepo.GetGroupMessageList(groupID, pagination, batchSize)
+		if err != nil {
+			return err
+		}
+		if err := s.resolveAuthors(authors, messages); err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if err := out.message(msg, authors[msg.Username]); err != nil {
+				return err
+			}
+		}
+		if len(messages) < batchSize {
+			break
+		}
+		last := messages[len(messages)-1]
+		pagination.Cursor = &util.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return out.end()
+}
+
+// StartExport queues a background export, or returns the one of the same history still running
+func (s *Service) StartExport(username string, groupID int, format entity.ExportFormat) (*entity.ChatExport, error) {
+	if err := s.exportRepo.FailStaleExports(time.Now().Add(-exportTimeout)); err != nil {
+		return nil, err
+	}
+	pending, err := s.exportRepo.FindPendingExport(groupID, username, format)
+	if err == nil {
+		return pending, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := s.deleteExpiredExports(); err != nil {
+		return nil, err
+	}
+
+	id, err := generateExportID()
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.exportRepo.CreateExport(&entity.ChatExport{
+		ID:        id,
+		GroupID:   groupID,
+		Username:  username,
+		Format:    format,
+		Status:    entity.ExportPending,
+		ExpiresAt: time.Now().Add(exportTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runExport(export)
+
+	return export, nil
+}
+
+// runExport streams the history straight into the export's file, so it is never held in memory
+func (s *Service) runExport(export *entity.ChatExport) {
+	path := filepath.Join(s.exportDir, export.ID+"."+string(export.Format))
+	if err := s.writeFile(path, export.GroupID, export.Format); err != nil {
+		fmt.Println("Failed to export chat:", err)
+		os.Remove(path)
+		if err := s.exportRepo.FailExport(export.ID, err.Error()); err != nil {
+			fmt.Println("Failed to record export failure:", err)
+		}
+		return
+	}
+
+	completed, err := s.exportRepo.CompleteExport(export.ID, path)
+	if err != nil {
+		fmt.Println("Failed to save export:", err)
+	}
+	if !completed {
+		os.Remove(path)
+	}
+}
+
+func (s *Service) writeFile(path string, groupID int, format entity.ExportFormat) error {
+	if err := os.MkdirAll(s.exportDir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(file, groupID, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *Service) deleteExpiredExports() error {
+	expired, err := s.exportRepo.DeleteExpiredExports()
+	if err != nil {
+		return err
+	}
+	for _, export := range expired {
+		if export.Path == "" {
+			continue
+		}
+		if err := os.Remove(export.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Println("Failed to remove export file:", err)
+		}
+	}
+	return nil
+}
+
+// GetExport only finds exports requested by the user for the given group
+func (s *Service) GetExport(username string, groupID int, exportID string) (*entity.ChatExport, error) {
+	if err := s.exportRepo.FailStaleExports(time.Now().Add(-exportTimeout)); err != nil {
+		return nil, err
+	}
+	export, err := s.exportRepo.GetExport(exportID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if export.Username != username || export.GroupID != groupID {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+func (s *Service) GetExportFile(username string, groupID int, exportID string) (*entity.ChatExport, error) {
+	export, err := s.GetExport(username, groupID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != entity.ExportReady {
+		return export, ErrExportNotReady
+	}
+	return export, nil
+}
+
+// resolveAuthors looks up the display names of the authors not seen in earlier batches
+func (s *Service) resolveAuthors(authors map[string]string, messages []*entity.Message) error {
+	var missing []string
+	for _, msg := range messages {
+		if _, ok := authors[msg.Username]; !ok {
+			authors[msg.Username] = msg.Username
+			missing = append(missing, msg.Username)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	users, err := s.userClient.FindUsers(missing)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.DisplayName != "" {
+			authors[u.Username] = u.DisplayName
+		}
+	}
+	return nil
+}
+
+func generateExportID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
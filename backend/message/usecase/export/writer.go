@@ -0,0 +1,267 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"message/entity"
+	"strings"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+// writer renders a history one message at a time, so exports can be streamed
+type writer interface {
+	begin(group *entity.Group) error
+	message(msg *entity.Message, displayName string) error
+	end() error
+}
+
+func newWriter(w io.Writer, format entity.ExportFormat) writer {
+	out := bufio.NewWriter(w)
+	switch format {
+	case entity.ExportHTML:
+		return &htmlWriter{w: out}
+	case entity.ExportText:
+		return &textWriter{w: out}
+	default:
+		return &jsonWriter{w: out}
+	}
+}
+
+type exportedGroup struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Direct bool   `json:"direct"`
+}
+
+type exportedAttachment struct {
+	Type      entity.AttachmentType `json:"type"`
+	URL       string                `json:"url,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Latitude  *float64              `json:"latitude,omitempty"`
+	Longitude *float64              `json:"longitude,omitempty"`
+	PostID    string                `json:"post_id,omitempty"`
+}
+
+type exportedMessage struct {
+	ID               int                  `json:"id"`
+	Username         string               `json:"username"`
+	DisplayName      string               `json:"display_name"`
+	Content          string               `json:"content"`
+	CreatedAt        time.Time            `json:"created_at"`
+	EditedAt         *time.Time           `json:"edited_at,omitempty"`
+	ReplyToMessageID *int                 `json:"reply_to_message_id,omitempty"`
+	System           bool                 `json:"system,omitempty"`
+	Deleted          bool                 `json:"deleted,omitempty"`
+	Encrypted        bool                 `json:"encrypted,omitempty"`
+	Attachments      []exportedAttachment `json:"attachments,omitempty"`
+}
+
+// The server never holds the keys of end-to-end encrypted messages, they are exported as a placeholder
+func isEncrypted(msg *entity.Message) bool {
+	return msg.Protocol == entity.ProtocolE2EE
+}
+
+// placeholder stands in for content that cannot be exported, empty when there is none
+func placeholder(msg *entity.Message) string {
+	switch {
+	case msg.DeletedAt != nil:
+		return "[deleted message]"
+	case isEncrypted(msg):
+		return "[end-to-end encrypted message]"
+	}
+	return ""
+}
+
+func attachmentLabel(a *entity.Attachment) string {
+	switch {
+	case a.URL != "":
+		return a.URL
+	case a.PostID != "":
+		return "post " + a.PostID
+	case a.Latitude != nil && a.Longitude != nil:
+		return fmt.Sprintf("%f, %f", *a.Latitude, *a.Longitude)
+	}
+	return a.Name
+}
+
+type jsonWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (j *jsonWriter) begin(group *entity.Group) error {
+	header, err := json.Marshal(exportedGroup{ID: group.ID, Name: group.Name, Direct: group.IsDirect})
+	if err != nil {
+		return err
+	}
+	exportedAt, err := json.Marshal(time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, `{"group":%s,"exported_at":%s,"messages":[`, header, exportedAt)
+	return err
+}
+
+func (j *jsonWriter) message(msg *entity.Message, displayName string) error {
+	out := exportedMessage{
+		ID:               msg.ID,
+		Username:         msg.Username,
+		DisplayName:      displayName,
+		CreatedAt:        msg.CreatedAt,
+		EditedAt:         msg.EditedAt,
+		ReplyToMessageID: msg.ReplyToMessageID,
+		System:           msg.System,
+		Deleted:          msg.DeletedAt != nil,
+		Encrypted:        isEncrypted(msg),
+	}
+	if msg.DeletedAt == nil {
+		out.Content = msg.Content
+		for _, a := range msg.Attachments {
+			out.Attachments = append(out.Attachments, exportedAttachment{
+				Type:      a.Type,
+				URL:       a.URL,
+				Name:      a.Name,
+				Latitude:  a.Latitude,
+				Longitude: a.Longitude,
+				PostID:    a.PostID,
+			})
+		}
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if err := j.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	j.count++
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonWriter) end() error {
+	if _, err := j.w.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+type textWriter struct {
+	w *bufio.Writer
+}
+
+func (t *textWriter) begin(group *entity.Group) error {
+	_, err := fmt.Fprintf(t.w, "Chat export: %s\nExported at %s\n\n", groupTitle(group), time.Now().UTC().Format(timeLayout))
+	return err
+}
+
+func (t *textWriter) message(msg *entity.Message, displayName string) error {
+	content := msg.Content
+	if p := placeholder(msg); p != "" {
+		content = p
+	}
+	line := fmt.Sprintf("[%s] %s (%s): %s", msg.CreatedAt.UTC().Format(timeLayout), displayName, msg.Username, content)
+	if msg.System {
+		line = fmt.Sprintf("[%s] * %s: %s", msg.CreatedAt.UTC().Format(timeLayout), displayName, content)
+	}
+	if msg.EditedAt != nil && msg.DeletedAt == nil {
+		line += " (edited)"
+	}
+	if _, err := fmt.Fprintln(t.w, line); err != nil {
+		return err
+	}
+
+	if msg.DeletedAt != nil {
+		return nil
+	}
+	for _, a := range msg.Attachments {
+		if _, err := fmt.Fprintf(t.w, "    [%s] %s\n", a.Type, attachmentLabel(a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *textWriter) end() error {
+	return t.w.Flush()
+}
+
+type htmlWriter struct {
+	w *bufio.Writer
+}
+
+func (h *htmlWriter) begin(group *entity.Group) error {
+	title := html.EscapeString(groupTitle(group))
+	_, err := fmt.Fprintf(h.w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; }
+.message { margin: 0.5rem 0; }
+.meta { color: #666; font-size: 0.8rem; }
+.system, .placeholder { color: #888; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p class="meta">Exported at %s</p>
+`, title, title, time.Now().UTC().Format(timeLayout))
+	return err
+}
+
+func (h *htmlWriter) message(msg *entity.Message, displayName string) error {
+	class := "message"
+	if msg.System {
+		class += " system"
+	}
+	meta := fmt.Sprintf("%s (%s) &middot; %s", html.EscapeString(displayName), html.EscapeString(msg.Username), msg.CreatedAt.UTC().Format(timeLayout))
+	if msg.EditedAt != nil && msg.DeletedAt == nil {
+		meta += " &middot; edited"
+	}
+
+	content := `<p>` + strings.ReplaceAll(html.EscapeString(msg.Content), "\n", "<br>") + `</p>`
+	if p := placeholder(msg); p != "" {
+		content = `<p class="placeholder">` + p + `</p>`
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<div class=\"%s\" id=\"m%d\">\n<div class=\"meta\">%s</div>\n%s\n", class, msg.ID, meta, content)
+	if msg.DeletedAt == nil {
+		for _, a := range msg.Attachments {
+			label := html.EscapeString(attachmentLabel(a))
+			if a.URL != "" {
+				fmt.Fprintf(&b, "<div>[%s] <a href=\"%s\">%s</a></div>\n", a.Type, html.EscapeString(a.URL), label)
+			} else {
+				fmt.Fprintf(&b, "<div>[%s] %s</div>\n", a.Type, label)
+			}
+		}
+	}
+	b.WriteString("</div>\n")
+
+	_, err := h.w.WriteString(b.String())
+	return err
+}
+
+func (h *htmlWriter) end() error {
+	if _, err := h.w.WriteString("</body>\n</html>\n"); err != nil {
+		return err
+	}
+	return h.w.Flush()
+}
+
+func groupTitle(group *entity.Group) string {
+	if group.Name == "" {
+		return fmt.Sprintf("Conversation %d", group.ID)
+	}
+	return group.Name
+}
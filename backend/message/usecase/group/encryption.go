@@ -0,0 +1,32 @@
+package group
+
+import "message/entity"
+
+// SetEncrypted switches a direct group between plaintext and end-to-end encrypted messages and
+// reports whether anything changed. Either side may switch, messages already sent stay as they are
+func (s *Service) SetEncrypted(actor string, groupID int, encrypted bool) (*entity.Group, bool, error) {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !group.IsDirect {
+		return nil, false, ErrNotDirectGroup
+	}
+	role, err := s.getRole(groupID, actor)
+	if err != nil {
+		return nil, false, err
+	}
+	if role == "" {
+		return nil, false, ErrPermissionDenied
+	}
+
+	if group.Encrypted == encrypted {
+		return group, false, nil
+	}
+	group.Encrypted = encrypted
+	group, err = s.groupRepo.UpdateGroup(group)
+	if err != nil {
+		return nil, false, err
+	}
+	return group, true, nil
+}
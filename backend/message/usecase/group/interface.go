@@ -3,20 +3,55 @@ package group
 import (
 	"message/entity"
 	"message/util"
+	"time"
 )
 
+// GroupUpdate holds the fields to change, nil ones are left as they are
+type GroupUpdate struct {
+	Name               *string
+	Avatar             *string
+	Description        *string
+	OnlyAdminsPost     *bool
+	OnlyAdminsEditInfo *bool
+}
+
 type UseCase interface {
 	GetGroup(groupID int) (*entity.Group, error)
-	UpdateGroup(groupID int, groupName string) (*entity.Group, error)
+	UpdateGroup(actor string, groupID int, update GroupUpdate) (*entity.Group, []string, error)
 	CreateGroup(ownername string, groupName string, members []string) (*entity.Group, error)
-	DeleteGroup(groupID int) error
+	DeleteGroup(actor string, groupID int) error
 
 	GetDirectGroup(userA string, userB string) (*entity.Group, error)
-	GetGroupsOfUser(username string, pagination util.Pagination) ([]*entity.Group, error)
+	GetGroupsOfUser(username string, archived bool, pagination util.Pagination) ([]*entity.Group, error)
+	GetGroupIDsOfUser(username string) ([]int, error)
 	CheckOwnership(username string, groupID int) (bool, error)
 	CheckMembership(username string, groupID int) (bool, error)
+	CanModerate(username string, groupID int) (bool, error)
+	CanPost(username string, groupID int) (bool, error)
 
 	GetMembers(groupID int) ([]string, error)
-	AddMember(groupID int, username string) (*entity.Group, error)
-	RemoveMember(groupID int, username string) (*entity.Group, error)
+	GetMembersOfGroups(groupIDs []int) (map[int][]*entity.GroupUser, error)
+	AddMember(actor string, groupID int, username string) (*entity.Group, error)
+	RemoveMember(actor string, groupID int, username string) (*entity.Group, error)
+	SetMemberRole(actor string, groupID int, username string, role entity.GroupRole) (*entity.Group, error)
+	TransferOwnership(actor string, groupID int, newOwner string) (*entity.Group, error)
+
+	GetMembership(groupID int, username string) (*entity.GroupUser, error)
+	GetMutedMembers(groupID int) ([]string, error)
+	MuteGroup(username string, groupID int, duration time.Duration) (*entity.GroupUser, error)
+	UnmuteGroup(username string, groupID int) (*entity.GroupUser, error)
+	SetArchived(username string, groupID int, archived bool) (*entity.GroupUser, error)
+	SetPinned(username string, groupID int, pinned bool) (*entity.GroupUser, error)
+
+	CreateInvite(actor string, groupID int, expiresIn time.Duration, maxUses int) (*entity.GroupInvite, error)
+	GetInvites(actor string, groupID int) ([]*entity.GroupInvite, error)
+	RevokeInvite(actor string, groupID int, code string) error
+	JoinByInvite(username string, code string) (*entity.Group, bool, error)
+	GetJoinRequests(actor string, groupID int) ([]*entity.JoinRequest, error)
+	ApproveJoinRequest(actor string, groupID int, username string) (*entity.Group, error)
+	DeclineJoinRequest(actor string, groupID int, username string) error
+	SetRequiresApproval(actor string, groupID int, requiresApproval bool) (*entity.Group, error)
+
+	SetRetention(actor string, groupID int, policy entity.RetentionPolicy, days int) (*entity.Group, bool, error)
+	SetEncrypted(actor string, groupID int, encrypted bool) (*entity.Group, bool, error)
 }
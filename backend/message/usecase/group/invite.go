@@ -0,0 +1,232 @@
+package group
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"message/entity"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInviteNotFound  = errors.New("invite does not exist or has been revoked")
+	ErrInviteExpired   = errors.New("invite has expired")
+	ErrInviteExhausted = errors.New("invite has reached its maximum number of uses")
+	ErrAlreadyMember   = errors.New("user is already a member of the group")
+	ErrInvalidInvite   = errors.New("invite expiry and max uses must not be negative")
+)
+
+func (s *Service) CreateInvite(actor string, groupID int, expiresIn time.Duration, maxUses int) (*entity.GroupInvite, error) {
+	if expiresIn < 0 || maxUses < 0 {
+		return nil, ErrInvalidInvite
+	}
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+	invite := &entity.GroupInvite{
+		Code:      code,
+		GroupID:   groupID,
+		CreatedBy: actor,
+		MaxUses:   maxUses,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	return s.inviteRepo.CreateInvite(invite)
+}
+
+func (s *Service) GetInvites(actor string, groupID int) ([]*entity.GroupInvite, error) {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.GetInvitesOfGroup(groupID)
+}
+
+func (s *Service) RevokeInvite(actor string, groupID int, code string) error {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return err
+	}
+	return s.inviteRepo.DeleteInvite(groupID, code)
+}
+
+// JoinByInvite adds the user to the group behind the code, or queues a join request when the
+// group requires approval, in which case the returned bool is true
+func (s *Service) JoinByInvite(username string, code string) (*entity.Group, bool, error) {
+	invite, err := s.inviteRepo.GetInvite(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+		return nil, false, ErrInviteExpired
+	}
+
+	group, err := s.groupRepo.GetGroup(invite.GroupID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	isMember, err := s.groupUserRepo.CheckUserInGroup(group.ID, username)
+	if err != nil {
+		return nil, false, err
+	}
+	if isMember {
+		return nil, false, ErrAlreadyMember
+	}
+
+	if group.RequiresApproval {
+		_, err = s.inviteRepo.GetJoinRequest(group.ID, username)
+		if err == nil {
+			return group, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	ok, err := s.inviteRepo.UseInvite(invite.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, ErrInviteExhausted
+	}
+
+	if group.RequiresApproval {
+		_, err = s.inviteRepo.CreateJoinRequest(&entity.JoinRequest{
+			GroupID:    group.ID,
+			Username:   username,
+			InviteCode: code,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		s.notifyAdmins(username, group.ID, "group:joinRequest:"+username)
+		return group, true, nil
+	}
+
+	err = s.groupUserRepo.AddUserToGroup(group.ID, username, entity.GroupRoleMember)
+	if err != nil {
+		return nil, false, err
+	}
+	return group, false, nil
+}
+
+func (s *Service) GetJoinRequests(actor string, groupID int) ([]*entity.JoinRequest, error) {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.GetJoinRequests(groupID)
+}
+
+func (s *Service) ApproveJoinRequest(actor string, groupID int, username string) (*entity.Group, error) {
+	group, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.inviteRepo.GetJoinRequest(groupID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.groupUserRepo.AddUserToGroup(groupID, username, entity.GroupRoleMember)
+	if err != nil {
+		return nil, err
+	}
+	err = s.inviteRepo.DeleteJoinRequest(groupID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(actor, username, groupID, "group:joinApproved:"+actor)
+	return group, nil
+}
+
+func (s *Service) DeclineJoinRequest(actor string, groupID int, username string) error {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.inviteRepo.GetJoinRequest(groupID, username)
+	if err != nil {
+		return err
+	}
+	return s.inviteRepo.DeleteJoinRequest(groupID, username)
+}
+
+func (s *Service) SetRequiresApproval(actor string, groupID int, requiresApproval bool) (*entity.Group, error) {
+	group, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	group.RequiresApproval = requiresApproval
+	return s.groupRepo.UpdateGroup(group)
+}
+
+// notifyAdmins is best effort, a failing noti service must not fail the join itself
+func (s *Service) notifyAdmins(sender string, groupID int, desc string) {
+	roles, err := s.groupUserRepo.GetRoles(groupID)
+	if err != nil {
+		fmt.Println("Failed to get group admins:", err)
+		return
+	}
+
+	muted, err := s.groupUserRepo.GetMutedUsers(groupID)
+	if err != nil {
+		fmt.Println("Failed to get muted members:", err)
+		return
+	}
+	for _, username := range muted {
+		delete(roles, username)
+	}
+
+	var admins []string
+	for username, role := range roles {
+		if roleRank(role) >= roleRank(entity.GroupRoleAdmin) {
+			admins = append(admins, username)
+		}
+	}
+	if len(admins) == 0 {
+		return
+	}
+
+	err = s.notiClient.CreateNotiToUsers(sender, admins, "group", desc, strconv.Itoa(groupID))
+	if err != nil {
+		fmt.Println("Failed to notify group admins:", err)
+	}
+}
+
+func (s *Service) notify(sender string, username string, groupID int, desc string) {
+	_, err := s.notiClient.CreateNoti(sender, username, "group", desc, strconv.Itoa(groupID))
+	if err != nil {
+		fmt.Println("Failed to notify user:", err)
+	}
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,61 @@
+package group
+
+import (
+	"errors"
+	"message/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Muting without a duration lasts until the member unmutes
+var mutedForever = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+func (s *Service) GetMembership(groupID int, username string) (*entity.GroupUser, error) {
+	groupUser, err := s.groupUserRepo.GetMembership(groupID, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	return groupUser, nil
+}
+
+// GetMutedMembers lists members who should not be alerted about new activity in the group
+func (s *Service) GetMutedMembers(groupID int) ([]string, error) {
+	return s.groupUserRepo.GetMutedUsers(groupID)
+}
+
+func (s *Service) MuteGroup(username string, groupID int, duration time.Duration) (*entity.GroupUser, error) {
+	mutedUntil := mutedForever
+	if duration > 0 {
+		mutedUntil = time.Now().Add(duration)
+	}
+	return s.updatePreferences(username, groupID, map[string]interface{}{"muted_until": mutedUntil})
+}
+
+func (s *Service) UnmuteGroup(username string, groupID int) (*entity.GroupUser, error) {
+	return s.updatePreferences(username, groupID, map[string]interface{}{"muted_until": nil})
+}
+
+func (s *Service) SetArchived(username string, groupID int, archived bool) (*entity.GroupUser, error) {
+	return s.updatePreferences(username, groupID, map[string]interface{}{"archived": archived})
+}
+
+func (s *Service) SetPinned(username string, groupID int, pinned bool) (*entity.GroupUser, error) {
+	return s.updatePreferences(username, groupID, map[string]interface{}{"pinned": pinned})
+}
+
+func (s *Service) updatePreferences(username string, groupID int, preferences map[string]interface{}) (*entity.GroupUser, error) {
+	_, err := s.GetMembership(groupID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.groupUserRepo.UpdatePreferences(groupID, username, preferences)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMembership(groupID, username)
+}
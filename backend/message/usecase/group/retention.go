@@ -0,0 +1,45 @@
+package group
+
+import "message/entity"
+
+// SetRetention changes how long the group keeps its messages and reports whether anything changed.
+// Either side of a direct group may change it, other groups reserve it to admins
+func (s *Service) SetRetention(actor string, groupID int, policy entity.RetentionPolicy, days int) (*entity.Group, bool, error) {
+	switch policy {
+	case entity.RetentionForever, entity.RetentionRead:
+		days = 0
+	case entity.RetentionDays:
+		if days < 1 || days > maxRetentionDays {
+			return nil, false, ErrInvalidRetention
+		}
+	default:
+		return nil, false, ErrInvalidRetention
+	}
+
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, false, err
+	}
+	minRole := entity.GroupRoleAdmin
+	if group.IsDirect {
+		minRole = entity.GroupRoleMember
+	}
+	role, err := s.getRole(groupID, actor)
+	if err != nil {
+		return nil, false, err
+	}
+	if role == "" || roleRank(role) < roleRank(minRole) {
+		return nil, false, ErrPermissionDenied
+	}
+
+	if group.RetentionPolicy == policy && group.RetentionDays == days {
+		return group, false, nil
+	}
+	group.RetentionPolicy = policy
+	group.RetentionDays = days
+	group, err = s.groupRepo.UpdateGroup(group)
+	if err != nil {
+		return nil, false, err
+	}
+	return group, true, nil
+}
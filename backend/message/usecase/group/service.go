@@ -1,20 +1,47 @@
 package group
 
 import (
+	"errors"
+	"message/api/client"
 	"message/entity"
 	"message/infrastructure/repository"
 	"message/util"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxDescriptionLength = 512
+	maxRetentionDays     = 365
+)
+
+var (
+	ErrPermissionDenied   = errors.New("you are not allowed to do this in the group")
+	ErrEmptyName          = errors.New("group name must not be empty")
+	ErrDescriptionTooLong = errors.New("group description must be at most 512 bytes")
+	ErrNotMember          = errors.New("user is not a member of the group")
+	ErrInvalidRole        = errors.New("role must be either admin or member")
+	ErrDirectGroup        = errors.New("direct groups have no roles or members to manage")
+	ErrBlocked            = errors.New("user is blocked")
+	ErrInvalidRetention   = errors.New("retention must be forever, read, or days between 1 and 365")
+	ErrNotDirectGroup     = errors.New("end-to-end encryption is only available in direct groups")
 )
 
 type Service struct {
 	groupRepo     *repository.GroupRepository
 	groupUserRepo *repository.GroupUserRepository
+	inviteRepo    *repository.InviteRepository
+	notiClient    client.NotiClient
+	userClient    client.UserClient
 }
 
-func NewService(groupRepo *repository.GroupRepository, groupUserRepo *repository.GroupUserRepository) *Service {
+func NewService(groupRepo *repository.GroupRepository, groupUserRepo *repository.GroupUserRepository, inviteRepo *repository.InviteRepository, notiClient client.NotiClient, userClient client.UserClient) *Service {
 	return &Service{
 		groupRepo:     groupRepo,
 		groupUserRepo: groupUserRepo,
+		inviteRepo:    inviteRepo,
+		notiClient:    notiClient,
+		userClient:    userClient,
 	}
 }
 
@@ -26,12 +53,62 @@ func (s *Service) GetGroup(groupID int) (*entity.Group, error) {
 	return group, nil
 }
 
-func (s *Service) UpdateGroup(groupID int, groupName string) (*entity.Group, error) {
-	group, err := s.groupRepo.UpdateGroup(&entity.Group{ID: groupID, Name: groupName})
+// UpdateGroup applies the update and returns the names of the fields that actually changed.
+// Settings are reserved to admins, info can be edited by any member unless OnlyAdminsEditInfo is on
+func (s *Service) UpdateGroup(actor string, groupID int, update GroupUpdate) (*entity.Group, []string, error) {
+	group, err := s.getManagedGroup(actor, groupID, entity.GroupRoleMember)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return group, nil
+
+	minRole := entity.GroupRoleMember
+	if group.OnlyAdminsEditInfo || update.OnlyAdminsPost != nil || update.OnlyAdminsEditInfo != nil {
+		minRole = entity.GroupRoleAdmin
+	}
+	role, err := s.getRole(groupID, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	if roleRank(role) < roleRank(minRole) {
+		return nil, nil, ErrPermissionDenied
+	}
+
+	var changes []string
+	if update.Name != nil && *update.Name != group.Name {
+		if *update.Name == "" {
+			return nil, nil, ErrEmptyName
+		}
+		group.Name = *update.Name
+		changes = append(changes, "name")
+	}
+	if update.Avatar != nil && *update.Avatar != group.Avatar {
+		group.Avatar = *update.Avatar
+		changes = append(changes, "avatar")
+	}
+	if update.Description != nil && *update.Description != group.Description {
+		if len(*update.Description) > maxDescriptionLength {
+			return nil, nil, ErrDescriptionTooLong
+		}
+		group.Description = *update.Description
+		changes = append(changes, "description")
+	}
+	if update.OnlyAdminsPost != nil && *update.OnlyAdminsPost != group.OnlyAdminsPost {
+		group.OnlyAdminsPost = *update.OnlyAdminsPost
+		changes = append(changes, "onlyAdminsPost")
+	}
+	if update.OnlyAdminsEditInfo != nil && *update.OnlyAdminsEditInfo != group.OnlyAdminsEditInfo {
+		group.OnlyAdminsEditInfo = *update.OnlyAdminsEditInfo
+		changes = append(changes, "onlyAdminsEditInfo")
+	}
+	if len(changes) == 0 {
+		return group, nil, nil
+	}
+
+	group, err = s.groupRepo.UpdateGroup(group)
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, changes, nil
 }
 
 func (s *Service) CreateGroup(ownername string, groupName string, members []string) (*entity.Group, error) {
@@ -39,8 +116,15 @@ func (s *Service) CreateGroup(ownername string, groupName string, members []stri
 	if err != nil {
 		return nil, err
 	}
+	err = s.groupUserRepo.AddUserToGroup(group.ID, ownername, entity.GroupRoleOwner)
+	if err != nil {
+		return nil, err
+	}
 	for _, member := range members {
-		err = s.groupUserRepo.AddUserToGroup(group.ID, member)
+		if member == ownername {
+			continue
+		}
+		err = s.groupUserRepo.AddUserToGroup(group.ID, member, entity.GroupRoleMember)
 		if err != nil {
 			return nil, err
 		}
@@ -48,7 +132,15 @@ func (s *Service) CreateGroup(ownername string, groupName string, members []stri
 	return group, nil
 }
 
-func (s *Service) DeleteGroup(groupID int) error {
+func (s *Service) DeleteGroup(actor string, groupID int) error {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleOwner)
+	if err != nil {
+		return err
+	}
+	return s.deleteGroup(groupID)
+}
+
+func (s *Service) deleteGroup(groupID int) error {
 	err := s.groupRepo.DeleteGroup(groupID)
 	if err != nil {
 		return err
@@ -59,10 +151,29 @@ func (s *Service) DeleteGroup(groupID int) error {
 		return err
 	}
 
+	err = s.inviteRepo.DeleteInvitesOfGroup(groupID)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// GetDirectGroup creates the direct group on first use, unless one of the users blocked the other.
+// An existing conversation stays readable after a block
 func (s *Service) GetDirectGroup(userA string, userB string) (*entity.Group, error) {
+	blocked, err := client.IsBlocked(s.userClient, userA, userB)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		group, err := s.groupUserRepo.FindDirectGroup(userA, userB)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBlocked
+		}
+		return group, err
+	}
+
 	group, err := s.groupUserRepo.GetDirectGroup(userA, userB)
 	if err != nil {
 		return nil, err
@@ -70,26 +181,80 @@ func (s *Service) GetDirectGroup(userA string, userB string) (*entity.Group, err
 	return group, nil
 }
 
-func (s *Service) GetGroupsOfUser(username string, pagination util.Pagination) ([]*entity.Group, error) {
-	users, err := s.groupUserRepo.GetGroupsOfUser(username, pagination)
+func (s *Service) GetGroupsOfUser(username string, archived bool, pagination util.Pagination) ([]*entity.Group, error) {
+	groups, err := s.groupUserRepo.GetGroupsOfUser(username, archived, pagination)
 	if err != nil {
 		return nil, err
 	}
-	return users, nil
+	return groups, nil
+}
+
+// GetGroupIDsOfUser includes archived groups, it is what realtime delivery is based on
+func (s *Service) GetGroupIDsOfUser(username string) ([]int, error) {
+	return s.groupUserRepo.GetGroupIDsOfUser(username)
 }
 
 func (s *Service) CheckOwnership(username string, groupID int) (bool, error) {
-	group, err := s.groupRepo.GetGroup(groupID)
+	role, err := s.getRole(groupID, username)
 	if err != nil {
 		return false, err
 	}
-	return group.OwnerName == username, nil
+	return role == entity.GroupRoleOwner, nil
 }
 
 func (s *Service) CheckMembership(username string, groupID int) (bool, error) {
 	return s.groupUserRepo.CheckUserInGroup(groupID, username)
 }
 
+// CanModerate tells whether the user may act on other members' content, which owners and admins can
+func (s *Service) CanModerate(username string, groupID int) (bool, error) {
+	role, err := s.getRole(groupID, username)
+	if err != nil {
+		return false, err
+	}
+	return roleRank(role) >= roleRank(entity.GroupRoleAdmin), nil
+}
+
+// CanPost tells whether the user may send messages, which only admins can do when OnlyAdminsPost is on
+func (s *Service) CanPost(username string, groupID int) (bool, error) {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	role, err := s.getRole(groupID, username)
+	if err != nil {
+		return false, err
+	}
+	if group.OnlyAdminsPost {
+		return roleRank(role) >= roleRank(entity.GroupRoleAdmin), nil
+	}
+	if group.IsDirect && role != "" {
+		return s.canWriteDirect(username, groupID)
+	}
+	return role != "", nil
+}
+
+// canWriteDirect refuses writing to a direct group once either side blocked the other
+func (s *Service) canWriteDirect(username string, groupID int) (bool, error) {
+	members, err := s.groupUserRepo.GetUsersInGroup(groupID)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range members {
+		if member == username {
+			continue
+		}
+		blocked, err := client.IsBlocked(s.userClient, username, member)
+		if err != nil || blocked {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 func (s *Service) GetMembers(groupID int) ([]string, error) {
 	users, err := s.groupUserRepo.GetUsersInGroup(groupID)
 	if err != nil {
@@ -99,18 +264,179 @@ func (s *Service) GetMembers(groupID int) ([]string, error) {
 	return users, nil
 }
 
-func (s *Service) AddMember(groupID int, username string) (*entity.Group, error) {
-	err := s.groupUserRepo.AddUserToGroup(groupID, username)
+func (s *Service) GetMembersOfGroups(groupIDs []int) (map[int][]*entity.GroupUser, error) {
+	members := make(map[int][]*entity.GroupUser, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return members, nil
+	}
+
+	groupUsers, err := s.groupUserRepo.GetMembersOfGroups(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, gu := range groupUsers {
+		members[gu.GroupID] = append(members[gu.GroupID], gu)
+	}
+	return members, nil
+}
+
+func (s *Service) AddMember(actor string, groupID int, username string) (*entity.Group, error) {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.groupUserRepo.AddUserToGroup(groupID, username, entity.GroupRoleMember)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(groupID)
+}
+
+// RemoveMember lets anyone leave, admins remove plain members and the owner remove anyone.
+// When the owner leaves, ownership passes on and the group is deleted once nobody is left
+func (s *Service) RemoveMember(actor string, groupID int, username string) (*entity.Group, error) {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.getRole(groupID, username)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrNotMember
+	}
+
+	if actor != username {
+		if group.IsDirect {
+			return nil, ErrDirectGroup
+		}
+		actorRole, err := s.getRole(groupID, actor)
+		if err != nil {
+			return nil, err
+		}
+		if roleRank(actorRole) < roleRank(entity.GroupRoleAdmin) || roleRank(actorRole) <= roleRank(role) {
+			return nil, ErrPermissionDenied
+		}
+	}
+
+	if role == entity.GroupRoleOwner {
+		successor, err := s.groupUserRepo.GetSuccessor(groupID, username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return group, s.deleteGroup(groupID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = s.groupUserRepo.TransferOwnership(groupID, username, successor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.groupUserRepo.RemoveUserFromGroup(groupID, username)
 	if err != nil {
 		return nil, err
 	}
 	return s.GetGroup(groupID)
 }
 
-func (s *Service) RemoveMember(groupID int, username string) (*entity.Group, error) {
-	err := s.groupUserRepo.RemoveUserFromGroup(groupID, username)
+// SetMemberRole promotes a member to admin or demotes an admin, only the owner can do it
+func (s *Service) SetMemberRole(actor string, groupID int, username string, role entity.GroupRole) (*entity.Group, error) {
+	if role != entity.GroupRoleAdmin && role != entity.GroupRoleMember {
+		return nil, ErrInvalidRole
+	}
+
+	group, err := s.getManagedGroup(actor, groupID, entity.GroupRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if actor == username {
+		return nil, ErrPermissionDenied
+	}
+
+	current, err := s.getRole(groupID, username)
+	if err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return nil, ErrNotMember
+	}
+
+	err = s.groupUserRepo.UpdateRole(groupID, username, role)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *Service) TransferOwnership(actor string, groupID int, newOwner string) (*entity.Group, error) {
+	_, err := s.getManagedGroup(actor, groupID, entity.GroupRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if actor == newOwner {
+		return s.GetGroup(groupID)
+	}
+
+	isMember, err := s.groupUserRepo.CheckUserInGroup(groupID, newOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	err = s.groupUserRepo.TransferOwnership(groupID, actor, newOwner)
 	if err != nil {
 		return nil, err
 	}
 	return s.GetGroup(groupID)
 }
+
+// getManagedGroup loads a non-direct group and makes sure the actor holds at least the given role in it
+func (s *Service) getManagedGroup(actor string, groupID int, minRole entity.GroupRole) (*entity.Group, error) {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.IsDirect {
+		return nil, ErrDirectGroup
+	}
+
+	role, err := s.getRole(groupID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if roleRank(role) < roleRank(minRole) {
+		return nil, ErrPermissionDenied
+	}
+	return group, nil
+}
+
+// getRole returns an empty role for users outside the group
+func (s *Service) getRole(groupID int, username string) (entity.GroupRole, error) {
+	role, err := s.groupUserRepo.GetRole(groupID, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func roleRank(role entity.GroupRole) int {
+	switch role {
+	case entity.GroupRoleOwner:
+		return 3
+	case entity.GroupRoleAdmin:
+		return 2
+	case entity.GroupRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
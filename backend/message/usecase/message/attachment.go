@@ -0,0 +1,64 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"message/entity"
+	"net/url"
+	"regexp"
+)
+
+const maxAttachmentsPerMessage = 10
+
+var maxAttachmentSize = map[entity.AttachmentType]int64{
+	entity.AttachmentImage: 10 << 20,
+	entity.AttachmentVideo: 100 << 20,
+	entity.AttachmentFile:  25 << 20,
+}
+
+var (
+	ErrEmptyMessage      = errors.New("message has no content nor attachments")
+	ErrInvalidAttachment = errors.New("invalid attachment")
+)
+
+var linkRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+func validateAttachments(attachments []*entity.Attachment) error {
+	if len(attachments) > maxAttachmentsPerMessage {
+		return fmt.Errorf("%w: at most %d attachments are allowed", ErrInvalidAttachment, maxAttachmentsPerMessage)
+	}
+
+	for _, a := range attachments {
+		switch a.Type {
+		case entity.AttachmentImage, entity.AttachmentVideo, entity.AttachmentFile:
+			if !isHttpURL(a.URL) {
+				return fmt.Errorf("%w: %s requires an http url", ErrInvalidAttachment, a.Type)
+			}
+			if a.Size <= 0 || a.Size > maxAttachmentSize[a.Type] {
+				return fmt.Errorf("%w: %s size must be between 1 and %d bytes", ErrInvalidAttachment, a.Type, maxAttachmentSize[a.Type])
+			}
+		case entity.AttachmentLocation:
+			if a.Latitude == nil || a.Longitude == nil ||
+				*a.Latitude < -90 || *a.Latitude > 90 || *a.Longitude < -180 || *a.Longitude > 180 {
+				return fmt.Errorf("%w: location requires a valid latitude and longitude", ErrInvalidAttachment)
+			}
+		case entity.AttachmentPost:
+			if a.PostID == "" {
+				return fmt.Errorf("%w: shared post requires a post id", ErrInvalidAttachment)
+			}
+		default:
+			return fmt.Errorf("%w: unknown type %q", ErrInvalidAttachment, a.Type)
+		}
+	}
+	return nil
+}
+
+func isHttpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// findLink returns the first link in the content, which is the one getting a preview
+func findLink(content string) string {
+	return linkRegex.FindString(content)
+}
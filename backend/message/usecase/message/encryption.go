@@ -0,0 +1,89 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"message/e2ee"
+	"message/entity"
+)
+
+var (
+	ErrEncryptedGroup   = errors.New("this group only accepts end-to-end encrypted messages")
+	ErrNotEncrypted     = errors.New("end-to-end encryption is not enabled in this group")
+	ErrInvalidEnvelope  = errors.New("invalid encrypted message")
+	ErrEncryptedMessage = errors.New("end-to-end encrypted messages cannot be edited")
+)
+
+// SendEncryptedMessage stores a message sealed by the sender's device. The server cannot read it,
+// it only checks that every envelope is addressed to a member and that each other member got one
+func (s *Service) SendEncryptedMessage(username string, groupID int, encrypted *e2ee.EncryptedMessage, replyToMessageID *int) (*entity.Message, error) {
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.IsDirect || !group.Encrypted {
+		return nil, ErrNotEncrypted
+	}
+	if err := encrypted.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEnvelope, err)
+	}
+
+	members, err := s.groupUserRepo.GetUsersInGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	sealedFor := make(map[string]bool, len(members))
+	for _, e := range encrypted.Envelopes {
+		sealedFor[e.Recipient] = true
+	}
+	isMember := make(map[string]bool, len(members))
+	for _, member := range members {
+		isMember[member] = true
+		if member != username && !sealedFor[member] {
+			return nil, fmt.Errorf("%w: no envelope for %s", ErrInvalidEnvelope, member)
+		}
+	}
+	for recipient := range sealedFor {
+		if !isMember[recipient] {
+			return nil, fmt.Errorf("%w: %s is not in this group", ErrInvalidEnvelope, recipient)
+		}
+	}
+
+	replyTo, err := s.getReplyTarget(groupID, replyToMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopes := make([]*entity.MessageEnvelope, 0, len(encrypted.Envelopes))
+	for _, e := range encrypted.Envelopes {
+		envelopes = append(envelopes, &entity.MessageEnvelope{
+			Recipient:    e.Recipient,
+			DeviceID:     e.DeviceID,
+			EphemeralKey: e.EphemeralKey,
+			Nonce:        e.Nonce,
+			Ciphertext:   e.Ciphertext,
+			Signature:    e.Signature,
+		})
+	}
+
+	msg, err := s.messageRepo.CreateMessage(&entity.Message{
+		Username:         username,
+		GroupID:          groupID,
+		ReplyToMessageID: replyToMessageID,
+		Protocol:         entity.ProtocolE2EE,
+		ProtocolVersion:  encrypted.Version,
+		SenderDevice:     encrypted.SenderDevice,
+		Envelopes:        envelopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.ReplyTo = replyTo
+
+	err = s.groupUserRepo.UpdateLastReadMessageID(groupID, username, msg.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
@@ -1,14 +1,31 @@
 package message
 
 import (
+	"message/e2ee"
 	"message/entity"
 	"message/util"
 )
 
 type UseCase interface {
-	SendMessage(username string, content string, groupID int) (*entity.Message, error)
-	SendDirectMessage(username string, oppUsername string, content string) (*entity.Message, error)
+	SendMessage(username string, content string, groupID int, attachments []*entity.Attachment, replyToMessageID *int) (*entity.Message, error)
+	SendSystemMessage(username string, groupID int, content string) (*entity.Message, error)
+	SendEncryptedMessage(username string, groupID int, encrypted *e2ee.EncryptedMessage, replyToMessageID *int) (*entity.Message, error)
+	SendDirectMessage(username string, oppUsername string, content string, attachments []*entity.Attachment, replyToMessageID *int) (*entity.Message, error)
 	GetDirectMessageList(username string, oppUsername string, pagination util.Pagination) ([]*entity.Message, error)
-	GetGroupMessageList(groupID int, pagination util.Pagination) ([]*entity.Message, error)
+	GetGroupMessageList(groupID int, pagination util.CursorPagination) ([]*entity.Message, string, error)
 	GetLastMessage(groupID int) (*entity.Message, error)
+	GetLastMessages(groupIDs []int) (map[int]*entity.Message, error)
+	SearchMessages(username string, query string, groupID *int, pagination util.CursorPagination) ([]*entity.MessageSearchResult, string, error)
+
+	GetMessage(messageID int) (*entity.Message, error)
+	EditMessage(messageID int, content string) (*entity.Message, error)
+	DeleteMessage(messageID int) (*entity.Message, error)
+	GetMessageHistory(messageID int) ([]*entity.MessageEdit, error)
+	AttachLinkPreview(messageID int, content string) (*entity.LinkPreview, error)
+
+	UpsertReaction(messageID int, username string, reactionType string) (*entity.Message, error)
+	DeleteReaction(messageID int, username string) (*entity.Message, error)
+
+	MarkAsRead(username string, groupID int, messageID int) (int, error)
+	CountUnreadMessagesOfGroups(username string, groupIDs []int) (map[int]int, error)
 }
@@ -0,0 +1,43 @@
+package message
+
+import (
+	"fmt"
+	"time"
+)
+
+const retentionBatchSize = 500
+
+// SweepExpiredMessages deletes every message past its group's retention in batches, and returns
+// the deleted message IDs by group
+func (s *Service) SweepExpiredMessages() (map[int][]int, error) {
+	expired := make(map[int][]int)
+	for {
+		messages, err := s.messageRepo.DeleteExpiredMessages(retentionBatchSize)
+		if err != nil {
+			return expired, err
+		}
+		for _, m := range messages {
+			expired[m.GroupID] = append(expired[m.GroupID], m.ID)
+		}
+		if len(messages) < retentionBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunRetentionSweeper sweeps expired messages every interval until the process exits,
+// handing each group's deleted IDs to onExpired
+func (s *Service) RunRetentionSweeper(interval time.Duration, onExpired func(groupID int, messageIDs []int)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := s.SweepExpiredMessages()
+		if err != nil {
+			fmt.Println("Failed to sweep expired messages:", err)
+		}
+		for groupID, messageIDs := range expired {
+			onExpired(groupID, messageIDs)
+		}
+	}
+}
@@ -2,53 +2,148 @@ package message
 
 import (
 	"errors"
+	"fmt"
+	"message/api/client"
 	"message/entity"
 	"message/infrastructure/repository"
 	"message/util"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrMessageDeleted  = errors.New("message has been deleted")
+	ErrInvalidReply    = errors.New("replied message does not belong to this group")
+	ErrInvalidReaction = errors.New("reaction must be a non-empty emoji of at most 32 bytes")
+	ErrEmptyQuery      = errors.New("search query must not be empty")
+	ErrNotInGroup      = errors.New("user is not a member of this group")
+	ErrSystemMessage   = errors.New("system messages cannot be edited")
+	ErrBlocked         = errors.New("user is blocked")
+	ErrMessageNotFound = errors.New("message not found in this group")
+)
+
 type Service struct {
-	messageRepo   *repository.MessageRepository
-	groupUserRepo *repository.GroupUserRepository
+	messageRepo       *repository.MessageRepository
+	groupRepo         *repository.GroupRepository
+	groupUserRepo     *repository.GroupUserRepository
+	linkPreviewClient client.LinkPreviewClient
+	userClient        client.UserClient
 }
 
-func NewService(messageRepo *repository.MessageRepository, groupUserRepo *repository.GroupUserRepository) *Service {
+func NewService(messageRepo *repository.MessageRepository, groupRepo *repository.GroupRepository, groupUserRepo *repository.GroupUserRepository, linkPreviewClient client.LinkPreviewClient, userClient client.UserClient) *Service {
 	return &Service{
-		messageRepo:   messageRepo,
-		groupUserRepo: groupUserRepo,
+		messageRepo:       messageRepo,
+		groupRepo:         groupRepo,
+		groupUserRepo:     groupUserRepo,
+		linkPreviewClient: linkPreviewClient,
+		userClient:        userClient,
+	}
+}
+
+func (s *Service) SendMessage(username string, content string, groupID int, attachments []*entity.Attachment, replyToMessageID *int) (*entity.Message, error) {
+	if content == "" && len(attachments) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if err := validateAttachments(attachments); err != nil {
+		return nil, err
+	}
+	group, err := s.groupRepo.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Encrypted {
+		return nil, ErrEncryptedGroup
+	}
+	replyTo, err := s.getReplyTarget(groupID, replyToMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.messageRepo.CreateMessage(&entity.Message{
+		Username:         username,
+		Content:          content,
+		GroupID:          groupID,
+		ReplyToMessageID: replyToMessageID,
+		Attachments:      attachments,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.ReplyTo = replyTo
+
+	err = s.groupUserRepo.UpdateLastReadMessageID(groupID, username, msg.ID)
+	if err != nil {
+		return nil, err
 	}
+
+	return msg, err
 }
 
-func (s *Service) SendMessage(username string, content string, groupID int) (*entity.Message, error) {
+// SendSystemMessage records a group event in the chat history on behalf of the user who caused it
+func (s *Service) SendSystemMessage(username string, groupID int, content string) (*entity.Message, error) {
 	msg, err := s.messageRepo.CreateMessage(&entity.Message{
 		Username: username,
 		Content:  content,
 		GroupID:  groupID,
+		System:   true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return msg, err
+	err = s.groupUserRepo.UpdateLastReadMessageID(groupID, username, msg.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
 }
 
-func (s *Service) SendDirectMessage(username string, oppUsername string, content string) (*entity.Message, error) {
+func (s *Service) SendDirectMessage(username string, oppUsername string, content string, attachments []*entity.Attachment, replyToMessageID *int) (*entity.Message, error) {
+	if content == "" && len(attachments) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if err := validateAttachments(attachments); err != nil {
+		return nil, err
+	}
+	blocked, err := client.IsBlocked(s.userClient, username, oppUsername)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrBlocked
+	}
+
 	group, err := s.groupUserRepo.GetDirectGroup(username, oppUsername)
 	if err != nil {
 		return nil, err
 	}
+	if group.Encrypted {
+		return nil, ErrEncryptedGroup
+	}
 	groupID := group.ID
+	replyTo, err := s.getReplyTarget(groupID, replyToMessageID)
+	if err != nil {
+		return nil, err
+	}
 
 	msg, err := s.messageRepo.CreateMessage(&entity.Message{
-		Username: username,
-		Content:  content,
-		GroupID:  groupID,
+		Username:         username,
+		Content:          content,
+		GroupID:          groupID,
+		ReplyToMessageID: replyToMessageID,
+		Attachments:      attachments,
 	})
 	if err != nil {
 		return nil, err
 	}
+	msg.ReplyTo = replyTo
+
+	err = s.groupUserRepo.UpdateLastReadMessageID(groupID, username, msg.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	return msg, err
 }
@@ -64,12 +159,69 @@ func (s *Service) GetDirectMessageList(username string, oppUsername string, pagi
 	return messages, nil
 }
 
-func (s *Service) GetGroupMessageList(groupID int, pagination util.Pagination) ([]*entity.Message, error) {
-	messages, err := s.messageRepo.GetGroupMessageList(groupID, pagination)
+// GetGroupMessageList returns a newest-first page of messages along with the cursor of the next page
+// in the same direction, which is empty when there is nothing left
+func (s *Service) GetGroupMessageList(groupID int, pagination util.CursorPagination) ([]*entity.Message, string, error) {
+	messages, err := s.messageRepo.GetGroupMessageList(groupID, pagination, pagination.Size+1)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return messages, nil
+
+	var nextCursor string
+	if len(messages) > pagination.Size {
+		messages = messages[:pagination.Size]
+		last := messages[len(messages)-1]
+		nextCursor = util.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	if pagination.Direction == util.After {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, nextCursor, nil
+}
+
+// SearchMessages looks through the groups the user belongs to, or only groupID when it is given.
+// Results are newest-first and paged backwards only
+func (s *Service) SearchMessages(username string, query string, groupID *int, pagination util.CursorPagination) ([]*entity.MessageSearchResult, string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, "", ErrEmptyQuery
+	}
+
+	var groupIDs []int
+	if groupID != nil {
+		isMember, err := s.groupUserRepo.CheckUserInGroup(*groupID, username)
+		if err != nil {
+			return nil, "", err
+		}
+		if !isMember {
+			return nil, "", ErrNotInGroup
+		}
+		groupIDs = []int{*groupID}
+	} else {
+		var err error
+		groupIDs, err = s.groupUserRepo.GetGroupIDsOfUser(username)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	results, err := s.messageRepo.SearchMessages(query, groupIDs, pagination.Cursor, pagination.Size+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if len(results) > pagination.Size {
+		results = results[:pagination.Size]
+		last := results[len(results)-1].Message
+		nextCursor = util.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return results, nextCursor, nil
 }
 
 func (s *Service) GetLastMessage(groupID int) (*entity.Message, error) {
@@ -82,3 +234,195 @@ func (s *Service) GetLastMessage(groupID int) (*entity.Message, error) {
 	}
 	return msg, nil
 }
+
+func (s *Service) GetLastMessages(groupIDs []int) (map[int]*entity.Message, error) {
+	lastMessages := make(map[int]*entity.Message, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return lastMessages, nil
+	}
+
+	messages, err := s.messageRepo.GetLastMessages(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		lastMessages[msg.GroupID] = msg
+	}
+	return lastMessages, nil
+}
+
+func (s *Service) GetMessage(messageID int) (*entity.Message, error) {
+	msg, err := s.messageRepo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *Service) EditMessage(messageID int, content string) (*entity.Message, error) {
+	msg, err := s.messageRepo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+	if msg.System {
+		return nil, ErrSystemMessage
+	}
+	if msg.Protocol == entity.ProtocolE2EE {
+		return nil, ErrEncryptedMessage
+	}
+
+	msg, err = s.messageRepo.UpdateMessageContent(msg, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// The preview of the old content no longer applies, AttachLinkPreview fetches the new one
+	if _, err := s.messageRepo.ReplaceLinkPreview(msg.ID, content, nil); err != nil {
+		return nil, err
+	}
+	msg.LinkPreview = nil
+
+	return msg, nil
+}
+
+// AttachLinkPreview fetches the preview of the first link in content and stores it on the message. It is
+// slow, so callers run it after the message went out. The preview is nil when there is none or the
+// message was edited or deleted in the meantime
+func (s *Service) AttachLinkPreview(messageID int, content string) (*entity.LinkPreview, error) {
+	preview := s.fetchLinkPreview(content)
+	if preview == nil {
+		return nil, nil
+	}
+
+	replaced, err := s.messageRepo.ReplaceLinkPreview(messageID, content, preview)
+	if err != nil {
+		return nil, err
+	}
+	if !replaced {
+		return nil, nil
+	}
+
+	return preview, nil
+}
+
+func (s *Service) DeleteMessage(messageID int) (*entity.Message, error) {
+	msg, err := s.messageRepo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return msg, nil
+	}
+	return s.messageRepo.DeleteMessage(msg)
+}
+
+func (s *Service) GetMessageHistory(messageID int) ([]*entity.MessageEdit, error) {
+	edits, err := s.messageRepo.GetMessageEdits(messageID)
+	if err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+func (s *Service) UpsertReaction(messageID int, username string, reactionType string) (*entity.Message, error) {
+	if reactionType == "" || len(reactionType) > 32 {
+		return nil, ErrInvalidReaction
+	}
+
+	msg, err := s.messageRepo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+
+	if err := s.messageRepo.UpsertReaction(messageID, username, reactionType); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *Service) DeleteReaction(messageID int, username string) (*entity.Message, error) {
+	msg, err := s.messageRepo.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.messageRepo.DeleteReaction(messageID, username); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *Service) getReplyTarget(groupID int, replyToMessageID *int) (*entity.Message, error) {
+	if replyToMessageID == nil {
+		return nil, nil
+	}
+	replyTo, err := s.messageRepo.GetMessage(*replyToMessageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidReply
+	}
+	if err != nil {
+		return nil, err
+	}
+	if replyTo.GroupID != groupID {
+		return nil, ErrInvalidReply
+	}
+	return replyTo, nil
+}
+
+// MarkAsRead moves the read marker of the user to messageID, or to the latest message when messageID is 0,
+// and returns the resulting marker
+func (s *Service) MarkAsRead(username string, groupID int, messageID int) (int, error) {
+	if messageID == 0 {
+		last, err := s.GetLastMessage(groupID)
+		if err != nil {
+			return 0, err
+		}
+		if last == nil {
+			return 0, nil
+		}
+		messageID = last.ID
+	} else {
+		msg, err := s.messageRepo.GetMessage(messageID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrMessageNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		if msg.GroupID != groupID {
+			return 0, ErrMessageNotFound
+		}
+	}
+
+	if err := s.groupUserRepo.UpdateLastReadMessageID(groupID, username, messageID); err != nil {
+		return 0, err
+	}
+	return s.groupUserRepo.GetLastReadMessageID(groupID, username)
+}
+
+func (s *Service) CountUnreadMessagesOfGroups(username string, groupIDs []int) (map[int]int, error) {
+	if len(groupIDs) == 0 {
+		return map[int]int{}, nil
+	}
+	return s.messageRepo.CountUnreadMessagesOfGroups(username, groupIDs)
+}
+
+// fetchLinkPreview is best effort, a message is still sent when its link cannot be previewed
+func (s *Service) fetchLinkPreview(content string) *entity.LinkPreview {
+	link := findLink(content)
+	if link == "" {
+		return nil
+	}
+	preview, err := s.linkPreviewClient.FetchPreview(link)
+	if err != nil {
+		fmt.Printf("Failed to fetch link preview of %s: %v\n", link, err)
+		return nil
+	}
+	return preview
+}
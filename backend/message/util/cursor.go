@@ -0,0 +1,91 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CursorDirection string
+
+const (
+	Before CursorDirection = "before"
+	After  CursorDirection = "after"
+)
+
+// Cursor points at a message by its (created_at, id) key, which is what message lists are ordered by
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+type CursorPagination struct {
+	Direction CursorDirection
+	Cursor    *Cursor
+	Size      int
+}
+
+func EncodeCursor(createdAt time.Time, id int) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// ExtractCursorPagination reads `before` or `after` and `size` from the query,
+// without a cursor the newest messages are returned
+func ExtractCursorPagination(ctx *gin.Context) (CursorPagination, error) {
+	sizeQuery := ctx.Query("size")
+	size, err := strconv.Atoi(sizeQuery)
+	if err != nil || size <= 0 {
+		size = 20
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	pagination := CursorPagination{Direction: Before, Size: size}
+
+	before, after := ctx.Query("before"), ctx.Query("after")
+	if before != "" && after != "" {
+		return pagination, errors.New("only one of before and after can be provided")
+	}
+
+	if before != "" {
+		pagination.Cursor, err = DecodeCursor(before)
+	} else if after != "" {
+		pagination.Direction = After
+		pagination.Cursor, err = DecodeCursor(after)
+	}
+	if err != nil {
+		return pagination, err
+	}
+
+	return pagination, nil
+}
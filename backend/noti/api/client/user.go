@@ -0,0 +1,50 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type UserClient interface {
+	GetBlockRelations(username string) ([]string, error)
+}
+
+type UserClientImpl struct {
+	userUrl string
+}
+
+func NewUserClient(userUrl string) UserClient {
+	return &UserClientImpl{
+		userUrl: userUrl,
+	}
+}
+
+// GetBlockRelations lists the users who either blocked or were blocked by the given user.
+// It fails closed: any failure to get the list is an error, so callers refuse the request
+// instead of treating the user as having no blocks
+func (c *UserClientImpl) GetBlockRelations(username string) ([]string, error) {
+	req, err := http.NewRequest("GET", c.userUrl+"/internal/user/blocks/related", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Username", username)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get block relations: %s", resp.Status)
+	}
+
+	var usernames []string
+	if err := json.NewDecoder(resp.Body).Decode(&usernames); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
@@ -1,6 +1,7 @@
 package noti
 
 import (
+	"errors"
 	"net/http"
 	"noti/api/client"
 	"noti/api/payload"
@@ -46,7 +47,11 @@ func CreateNoti(ctx *gin.Context, notiService noti.UseCase, wsService client.WsC
 		return
 	}
 
-	notification, err := notiService.CreateNoti(body.Username, body.Icon, body.Desc, body.Link)
+	notification, err := notiService.CreateNoti(body.Sender, body.Username, body.Icon, body.Desc, body.Link)
+	if errors.Is(err, noti.ErrBlockedSender) {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification"})
 		return
@@ -66,7 +71,7 @@ func CreateNotiToUsers(ctx *gin.Context, notiService noti.UseCase, wsService cli
 		return
 	}
 
-	notis, err := notiService.CreateNotiToUsers(body.Usernames, body.Icon, body.Desc, body.Link)
+	notis, err := notiService.CreateNotiToUsers(body.Sender, body.Usernames, body.Icon, body.Desc, body.Link)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notifications"})
 		return
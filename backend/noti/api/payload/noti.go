@@ -1,6 +1,7 @@
 package payload
 
 type NotiCreateRequest struct {
+	Sender   string `json:"sender"`
 	Username string `json:"username"`
 	Icon     string `json:"icon"`
 	Desc     string `json:"desc"`
@@ -8,6 +9,7 @@ type NotiCreateRequest struct {
 }
 
 type NotiToUsersCreateRequest struct {
+	Sender    string   `json:"sender"`
 	Usernames []string `json:"usernames"`
 	Icon      string   `json:"icon"`
 	Desc      string   `json:"desc"`
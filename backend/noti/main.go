@@ -30,10 +30,11 @@ func makeHandler() *gin.Engine {
 		panic(fmt.Sprintf("failed to connect database %v", err.Error()))
 	}
 
-	wsClient := client.NewWsClient("http://gateway:8080")
+	wsClient := client.NewWsClient("http://gateway:8081")
+	userClient := client.NewUserClient("http://user:8080")
 
 	notiRepo := notiRepo.NewRepository(db)
-	notiService := notiService.NewService(notiRepo)
+	notiService := notiService.NewService(notiRepo, userClient)
 	noti.MakeHandler(app, notiService, wsClient)
 
 	return app
@@ -1,15 +1,19 @@
 package noti
 
 import (
+	"errors"
 	"noti/entity"
 	"noti/util"
 )
 
+// ErrBlockedSender means the receiver has a block relation with the sender, so no notification was created
+var ErrBlockedSender = errors.New("notification sender is blocked")
+
 type UseCase interface {
 	GetNoti(id int) (*entity.Notification, error)
 	GetNotisOfUser(username string, pagination util.Pagination) ([]*entity.Notification, error)
-	CreateNoti(username, icon, desc, link string) (*entity.Notification, error)
-	CreateNotiToUsers(usernames []string, icon, desc, link string) ([]*entity.Notification, error)
+	CreateNoti(sender, username, icon, desc, link string) (*entity.Notification, error)
+	CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) ([]*entity.Notification, error)
 	UpdateNoti(id int, read bool) (*entity.Notification, error)
 	DeleteNoti(id int) error
 }
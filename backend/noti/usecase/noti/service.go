@@ -1,18 +1,21 @@
 package noti
 
 import (
+	"noti/api/client"
 	"noti/entity"
 	"noti/infrastructure/repository/noti"
 	"noti/util"
 )
 
 type Service struct {
-	notiRepo *noti.NotificationRepository
+	notiRepo   *noti.NotificationRepository
+	userClient client.UserClient
 }
 
-func NewService(notiRepo *noti.NotificationRepository) *Service {
+func NewService(notiRepo *noti.NotificationRepository, userClient client.UserClient) *Service {
 	return &Service{
-		notiRepo: notiRepo,
+		notiRepo:   notiRepo,
+		userClient: userClient,
 	}
 }
 
@@ -32,7 +35,15 @@ func (s *Service) GetNotisOfUser(username string, pagination util.Pagination) ([
 	return notis, nil
 }
 
-func (s *Service) CreateNoti(username, icon, desc, link string) (*entity.Notification, error) {
+func (s *Service) CreateNoti(sender, username, icon, desc, link string) (*entity.Notification, error) {
+	recipients, err := s.filterBlocked(sender, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, ErrBlockedSender
+	}
+
 	usr, err := s.notiRepo.CreateNotification(username, icon, desc, link)
 	if err != nil {
 		return nil, err
@@ -40,8 +51,39 @@ func (s *Service) CreateNoti(username, icon, desc, link string) (*entity.Notific
 	return usr, nil
 }
 
-func (s *Service) CreateNotiToUsers(usernames []string, icon, desc, link string) ([]*entity.Notification, error) {
-	return s.notiRepo.CreateNotificationToUsers(usernames, icon, desc, link)
+func (s *Service) CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) ([]*entity.Notification, error) {
+	recipients, err := s.filterBlocked(sender, usernames)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return []*entity.Notification{}, nil
+	}
+	return s.notiRepo.CreateNotificationToUsers(recipients, icon, desc, link)
+}
+
+// filterBlocked drops the recipients that have a block relation with the sender.
+// Notifications without a sender come from the system and are never filtered
+func (s *Service) filterBlocked(sender string, usernames []string) ([]string, error) {
+	if sender == "" {
+		return usernames, nil
+	}
+	related, err := s.userClient.GetBlockRelations(sender)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]bool, len(related))
+	for _, username := range related {
+		blocked[username] = true
+	}
+
+	recipients := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		if !blocked[username] {
+			recipients = append(recipients, username)
+		}
+	}
+	return recipients, nil
 }
 
 func (s *Service) UpdateNoti(id int, read bool) (*entity.Notification, error) {
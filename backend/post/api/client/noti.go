@@ -8,8 +8,9 @@ import (
 )
 
 type NotiClient interface {
-	CreateNoti(username, icon, desc, link string) (*presenter.Notification, error)
-	CreateNotiToUsers(usernames []string, icon, desc, link string) error
+	// CreateNoti returns a nil notification when the receiver has a block relation with the sender
+	CreateNoti(sender, username, icon, desc, link string) (*presenter.Notification, error)
+	CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) error
 }
 
 type NotiClientImpl struct {
@@ -22,8 +23,9 @@ func NewNotiClient(notiUrl string) NotiClient {
 	}
 }
 
-func (c *NotiClientImpl) CreateNoti(username, icon, desc, link string) (*presenter.Notification, error) {
+func (c *NotiClientImpl) CreateNoti(sender, username, icon, desc, link string) (*presenter.Notification, error) {
 	body, err := json.Marshal(&presenter.NotiCreateRequest{
+		Sender:   sender,
 		Username: username,
 		Icon:     icon,
 		Desc:     desc,
@@ -46,6 +48,9 @@ func (c *NotiClientImpl) CreateNoti(username, icon, desc, link string) (*present
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
 
 	var noti presenter.Notification
 	if err := json.NewDecoder(resp.Body).Decode(&noti); err != nil {
@@ -55,8 +60,9 @@ func (c *NotiClientImpl) CreateNoti(username, icon, desc, link string) (*present
 	return &noti, nil
 }
 
-func (c *NotiClientImpl) CreateNotiToUsers(usernames []string, icon, desc, link string) error {
+func (c *NotiClientImpl) CreateNotiToUsers(sender string, usernames []string, icon, desc, link string) error {
 	body, err := json.Marshal(&presenter.NotiToUsersCreateRequest{
+		Sender:    sender,
 		Usernames: usernames,
 		Icon:      icon,
 		Desc:      desc,
@@ -3,12 +3,17 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"post/api/presenter"
 )
 
 type UserClient interface {
 	FindUsers([]string) ([]*presenter.User, error)
+	GetBlockRelations(username string) ([]string, error)
+	GetFriendUsernames(username string) ([]string, error)
+	// GetPets leaves out the pets that do not exist anymore
+	GetPets(ids []uint) ([]*presenter.Pet, error)
 }
 
 type UserClientImpl struct {
@@ -44,3 +49,82 @@ func (c *UserClientImpl) FindUsers(usernames []string) ([]*presenter.User, error
 
 	return users, nil
 }
+
+// GetBlockRelations lists the users who either blocked or were blocked by the given user.
+// It fails closed: any failure to get the list is an error, so callers refuse the request
+// instead of treating the user as having no blocks
+func (c *UserClientImpl) GetBlockRelations(username string) ([]string, error) {
+	req, err := http.NewRequest("GET", c.userUrl+"/internal/user/blocks/related", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Username", username)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get block relations: %s", resp.Status)
+	}
+
+	var usernames []string
+	if err := json.NewDecoder(resp.Body).Decode(&usernames); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
+
+func (c *UserClientImpl) GetFriendUsernames(username string) ([]string, error) {
+	req, err := http.NewRequest("GET", c.userUrl+"/api/user/friends/usernames", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Username", username)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get friends: %s", resp.Status)
+	}
+
+	var usernames []string
+	if err := json.NewDecoder(resp.Body).Decode(&usernames); err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
+
+func (c *UserClientImpl) GetPets(ids []uint) ([]*presenter.Pet, error) {
+	body, err := json.Marshal(&presenter.GetPetListRequest{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(c.userUrl+"/internal/user/pets/list", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get pets: %s", resp.Status)
+	}
+
+	var pets []*presenter.Pet
+	if err := json.NewDecoder(resp.Body).Decode(&pets); err != nil {
+		return nil, err
+	}
+
+	return pets, nil
+}
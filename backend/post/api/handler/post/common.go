@@ -2,20 +2,149 @@ package post
 
 import (
 	"errors"
+	"fmt"
 	"post/api/client"
 	"post/api/presenter"
 	"post/entity"
+	"post/usecase/post"
+	"post/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func ListPostEntityToPresenter(posts []*entity.Post, users map[string]*presenter.User) []*presenter.Post {
+// GetViewer looks up the friends of the viewer, who open up friends-only posts, and the users in a
+// block relation with them, whose posts and comments they must not see. Anonymous viewers get the zero viewer
+func GetViewer(c *gin.Context, userClient client.UserClient) (entity.Viewer, error) {
+	username, ok := util.TryGetUsername(c)
+	if !ok || username == "" {
+		return entity.Viewer{}, nil
+	}
+
+	friends, err := userClient.GetFriendUsernames(username)
+	if err != nil {
+		return entity.Viewer{}, err
+	}
+	hidden, err := userClient.GetBlockRelations(username)
+	if err != nil {
+		return entity.Viewer{}, err
+	}
+	return entity.Viewer{Username: username, Friends: friends, Hidden: hidden}, nil
+}
+
+// ExtractPostSearch reads the search from the query: `q`, `type`, `resolved`, `author`, `sort` and
+// the `from` and `to` creation dates, either RFC 3339 or plain dates that cover the whole day
+func ExtractPostSearch(c *gin.Context) (entity.PostSearch, error) {
+	search := entity.PostSearch{
+		Query:  c.Query("q"),
+		Type:   entity.PostType(c.Query("type")),
+		Author: c.Query("author"),
+		Sort:   entity.SearchSort(c.Query("sort")),
+	}
+
+	if resolved := c.Query("resolved"); resolved != "" {
+		value, err := strconv.ParseBool(resolved)
+		if err != nil {
+			return search, fmt.Errorf("invalid resolved: %w", err)
+		}
+		search.Resolved = &value
+	}
+
+	var err error
+	if search.From, err = parseSearchDate(c.Query("from"), false); err != nil {
+		return search, fmt.Errorf("invalid from: %w", err)
+	}
+	if search.To, err = parseSearchDate(c.Query("to"), true); err != nil {
+		return search, fmt.Errorf("invalid to: %w", err)
+	}
+	return search, nil
+}
+
+func parseSearchDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// GetViewerInteractions maps the posts onto the reaction the viewer left on them, nothing for anonymous viewers
+func GetViewerInteractions(c *gin.Context, postService *post.Service, posts []*entity.Post) (map[primitive.ObjectID]entity.InteractionType, error) {
+	username, ok := util.TryGetUsername(c)
+	if !ok || username == "" {
+		return nil, nil
+	}
+	return postService.GetViewerInteractions(c.Request.Context(), username, posts)
+}
+
+// GetViewerCommentInteractions maps the comments onto the reaction the viewer left on them, nothing for anonymous viewers
+func GetViewerCommentInteractions(c *gin.Context, postService *post.Service, comments []*entity.Comment) (map[primitive.ObjectID]entity.InteractionType, error) {
+	username, ok := util.TryGetUsername(c)
+	if !ok || username == "" {
+		return nil, nil
+	}
+	return postService.GetViewerCommentInteractions(c.Request.Context(), username, comments)
+}
+
+// GetPets resolves the pets the posts refer to by id, without asking the user service when none does
+func GetPets(posts []*entity.Post, userClient client.UserClient) (map[uint]*presenter.Pet, error) {
+	var ids []uint
+	for _, p := range posts {
+		if p.PetID != nil {
+			ids = append(ids, *p.PetID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pets, err := userClient.GetPets(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	petDict := make(map[uint]*presenter.Pet, len(pets))
+	for _, p := range pets {
+		petDict[p.ID] = p
+	}
+	return petDict, nil
+}
+
+func ListPostEntityToPresenter(posts []*entity.Post, users map[string]*presenter.User, pets map[uint]*presenter.Pet, viewer string, reactions map[primitive.ObjectID]entity.InteractionType) []*presenter.Post {
 	rPosts := make([]*presenter.Post, len(posts))
 	for i, p := range posts {
-		rPosts[i] = PostEntityToPresenter(p, users[p.Username])
+		rPosts[i] = PostEntityToPresenter(p, users[p.Username], pets, viewer, reactions)
 	}
 	return rPosts
 }
 
-func PostEntityToPresenter(post *entity.Post, user *presenter.User) *presenter.Post {
+func PostEntityToPresenter(post *entity.Post, user *presenter.User, pets map[uint]*presenter.Pet, viewer string, reactions map[primitive.ObjectID]entity.InteractionType) *presenter.Post {
+	var viewerInteraction *entity.InteractionType
+	if itype, ok := reactions[post.ID]; ok {
+		viewerInteraction = &itype
+	}
+
+	var audience []string
+	if viewer != "" && viewer == post.Username {
+		audience = post.Audience
+	}
+
+	var pet *presenter.Pet
+	if post.PetID != nil {
+		pet = pets[*post.PetID]
+	}
+
 	return &presenter.Post{
 		ID:          post.ID,
 		Type:        post.Type,
@@ -26,9 +155,17 @@ func PostEntityToPresenter(post *entity.Post, user *presenter.User) *presenter.P
 		Medias:      post.Medias,
 		CreatedAt:   post.CreatedAt,
 		UpdatedAt:   post.UpdatedAt,
+		Pet:         pet,
+
+		Visibility: post.Visibility,
+		Audience:   audience,
+		Tags:       post.Tags,
+		Mentions:   post.Mentions,
 
-		Interactions: post.Interactions,
-		CommentNum:   len(post.Comments),
+		InteractionCounts: presenter.InteractionCountsEntityToPresenter(post.InteractionCounts),
+		InteractionNum:    post.InteractionCount,
+		ViewerInteraction: viewerInteraction,
+		CommentNum:        post.CommentCount,
 
 		LostAt:       post.LostAt,
 		Area:         presenter.LocationEntityToPresenter(post.Area),
@@ -39,7 +176,12 @@ func PostEntityToPresenter(post *entity.Post, user *presenter.User) *presenter.P
 	}
 }
 
-func ListPostEntityToPresenterWithClient(posts []*entity.Post, userClient client.UserClient) ([]*presenter.Post, error) {
+func ListPostEntityToPresenterWithClient(c *gin.Context, posts []*entity.Post, postService *post.Service, userClient client.UserClient) ([]*presenter.Post, error) {
+	reactions, err := GetViewerInteractions(c, postService, posts)
+	if err != nil {
+		return nil, err
+	}
+
 	usernames := make([]string, len(posts))
 	for i, p := range posts {
 		usernames[i] = p.Username
@@ -55,14 +197,21 @@ func ListPostEntityToPresenterWithClient(posts []*entity.Post, userClient client
 		userDict[u.Username] = u
 	}
 
-	rPosts := make([]*presenter.Post, len(posts))
-	for i, p := range posts {
-		rPosts[i] = PostEntityToPresenter(p, userDict[p.Username])
+	pets, err := GetPets(posts, userClient)
+	if err != nil {
+		return nil, err
 	}
-	return rPosts, nil
+
+	viewer, _ := util.TryGetUsername(c)
+	return ListPostEntityToPresenter(posts, userDict, pets, viewer, reactions), nil
 }
 
-func PostEntityToPresenterWithClient(post *entity.Post, userClient client.UserClient) (*presenter.Post, error) {
+func PostEntityToPresenterWithClient(c *gin.Context, post *entity.Post, postService *post.Service, userClient client.UserClient) (*presenter.Post, error) {
+	reactions, err := GetViewerInteractions(c, postService, []*entity.Post{post})
+	if err != nil {
+		return nil, err
+	}
+
 	users, err := userClient.FindUsers([]string{post.Username})
 	if err != nil {
 		return nil, err
@@ -72,23 +221,50 @@ func PostEntityToPresenterWithClient(post *entity.Post, userClient client.UserCl
 	}
 	user := users[0]
 
-	return PostEntityToPresenter(post, user), nil
+	pets, err := GetPets([]*entity.Post{post}, userClient)
+	if err != nil {
+		return nil, err
+	}
+
+	viewer, _ := util.TryGetUsername(c)
+	return PostEntityToPresenter(post, user, pets, viewer, reactions), nil
 }
 
-func CommentEntityToPresenter(comment entity.Comment, user *presenter.User) *presenter.Comment {
+func CommentEntityToPresenter(comment *entity.Comment, user *presenter.User, reactions map[primitive.ObjectID]entity.InteractionType) *presenter.Comment {
+	var viewerInteraction *entity.InteractionType
+	if itype, ok := reactions[comment.ID]; ok {
+		viewerInteraction = &itype
+	}
+
 	return &presenter.Comment{
+		ID:          comment.ID,
+		PostID:      comment.PostID,
 		Username:    comment.Username,
 		DisplayName: user.DisplayName,
 		Avatar:      user.Avatar,
 		Content:     comment.Content,
 		CreatedAt:   comment.CreatedAt,
+		EditedAt:    comment.EditedAt,
+
+		ParentID:   comment.ParentID,
+		Depth:      comment.Depth(),
+		ReplyCount: comment.ReplyCount,
+
+		InteractionCounts: presenter.InteractionCountsEntityToPresenter(comment.InteractionCounts),
+		InteractionNum:    comment.InteractionCount,
+		ViewerInteraction: viewerInteraction,
 	}
 }
 
-func ListCommentEntityToPresenterWithClient(comments []entity.Comment, userClient client.UserClient) ([]*presenter.Comment, error) {
+func ListCommentEntityToPresenterWithClient(c *gin.Context, comments []*entity.Comment, postService *post.Service, userClient client.UserClient) ([]*presenter.Comment, error) {
+	reactions, err := GetViewerCommentInteractions(c, postService, comments)
+	if err != nil {
+		return nil, err
+	}
+
 	usernames := make([]string, len(comments))
-	for i, c := range comments {
-		usernames[i] = c.Username
+	for i, comment := range comments {
+		usernames[i] = comment.Username
 	}
 
 	users, err := userClient.FindUsers(usernames)
@@ -102,8 +278,56 @@ func ListCommentEntityToPresenterWithClient(comments []entity.Comment, userClien
 	}
 
 	pComments := make([]*presenter.Comment, len(comments))
-	for i, c := range comments {
-		pComments[i] = CommentEntityToPresenter(c, userDict[c.Username])
+	for i, comment := range comments {
+		pComments[i] = CommentEntityToPresenter(comment, userDict[comment.Username], reactions)
 	}
 	return pComments, nil
 }
+
+func CommentEntityToPresenterWithClient(c *gin.Context, comment *entity.Comment, postService *post.Service, userClient client.UserClient) (*presenter.Comment, error) {
+	reactions, err := GetViewerCommentInteractions(c, postService, []*entity.Comment{comment})
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := userClient.FindUsers([]string{comment.Username})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("user not found")
+	}
+
+	return CommentEntityToPresenter(comment, users[0], reactions), nil
+}
+
+func ListInteractionEntityToPresenterWithClient(interactions []*entity.PostInteraction, userClient client.UserClient) ([]*presenter.Interaction, error) {
+	usernames := make([]string, len(interactions))
+	for i, it := range interactions {
+		usernames[i] = it.Username
+	}
+
+	users, err := userClient.FindUsers(usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	userDict := make(map[string]*presenter.User, len(users))
+	for _, u := range users {
+		userDict[u.Username] = u
+	}
+
+	pInteractions := make([]*presenter.Interaction, len(interactions))
+	for i, it := range interactions {
+		pInteractions[i] = &presenter.Interaction{
+			Username:  it.Username,
+			Type:      it.Type,
+			CreatedAt: it.CreatedAt,
+		}
+		if u, ok := userDict[it.Username]; ok {
+			pInteractions[i].DisplayName = u.DisplayName
+			pInteractions[i].Avatar = u.Avatar
+		}
+	}
+	return pInteractions, nil
+}
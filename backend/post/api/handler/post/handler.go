@@ -9,7 +9,7 @@ import (
 )
 
 func MakeHandler(app *gin.Engine, postService *post.Service, userClient client.UserClient) {
-	postGroup := app.Group("/api/post")
+	postGroup := app.Group("/api/post", middleware.AuthorizeMiddleware())
 	{
 		postGroup.GET("/:postID", func(c *gin.Context) {
 			GetPost(c, postService, userClient)
@@ -19,6 +19,18 @@ func MakeHandler(app *gin.Engine, postService *post.Service, userClient client.U
 			GetNearLostPosts(c, postService, userClient)
 		})
 
+		postGroup.GET("/search", func(c *gin.Context) {
+			SearchPosts(c, postService, userClient)
+		})
+
+		postGroup.GET("/tag/:tag", func(c *gin.Context) {
+			GetPostsByTag(c, postService, userClient)
+		})
+
+		postGroup.GET("/tags/trending", func(c *gin.Context) {
+			GetTrendingTags(c, postService)
+		})
+
 		postGroup.GET("/ofUser/:username/participated", func(c *gin.Context) {
 			GetParticipatedPostsOfUser(c, postService, userClient)
 		})
@@ -31,12 +43,24 @@ func MakeHandler(app *gin.Engine, postService *post.Service, userClient client.U
 			GetPostsOfUsers(c, postService, userClient)
 		})
 
+		postGroup.GET("/:postID/interactions", func(c *gin.Context) {
+			GetInteractions(c, postService, userClient)
+		})
+
 		postGroup.GET("/:postID/comments", func(c *gin.Context) {
 			GetComments(c, postService, userClient)
 		})
 
+		postGroup.GET("/:postID/comments/:commentID/replies", func(c *gin.Context) {
+			GetReplies(c, postService, userClient)
+		})
+
 		authGroup := postGroup.Group("", middleware.MustAuthorizeMiddleware())
 
+		authGroup.GET("/feed", func(c *gin.Context) {
+			GetFeed(c, postService, userClient)
+		})
+
 		authGroup.POST("/blog", func(c *gin.Context) {
 			CreateBlogPost(c, postService)
 		})
@@ -53,16 +77,36 @@ func MakeHandler(app *gin.Engine, postService *post.Service, userClient client.U
 			PatchLostFoundStatus(c, postService)
 		})
 
+		authGroup.PATCH("/:postID/visibility", func(c *gin.Context) {
+			PatchVisibility(c, postService)
+		})
+
 		authGroup.DELETE("", func(c *gin.Context) {
 			DeletePost(c, postService)
 		})
 
 		authGroup.POST("/:postID/comments", func(c *gin.Context) {
-			CreateComment(c, postService)
+			CreateComment(c, postService, userClient)
+		})
+
+		authGroup.PATCH("/:postID/comments/:commentID", func(c *gin.Context) {
+			EditComment(c, postService, userClient)
+		})
+
+		authGroup.DELETE("/:postID/comments/:commentID", func(c *gin.Context) {
+			DeleteComment(c, postService)
+		})
+
+		authGroup.POST("/:postID/comments/:commentID/interactions", func(c *gin.Context) {
+			UpsertCommentInteraction(c, postService, userClient)
+		})
+
+		authGroup.DELETE("/:postID/comments/:commentID/interactions", func(c *gin.Context) {
+			DeleteCommentInteraction(c, postService)
 		})
 
 		authGroup.POST("/:postID/interactions", func(c *gin.Context) {
-			UpsertInteraction(c, postService)
+			UpsertInteraction(c, postService, userClient)
 		})
 
 		authGroup.DELETE("/:postID/interactions", func(c *gin.Context) {
@@ -70,11 +114,11 @@ func MakeHandler(app *gin.Engine, postService *post.Service, userClient client.U
 		})
 
 		authGroup.POST("/:postID/participation", func(c *gin.Context) {
-			Participate(c, postService)
+			Participate(c, postService, userClient)
 		})
 
 		authGroup.DELETE("/:postID/participation", func(c *gin.Context) {
-			Unparticipate(c, postService)
+			Unparticipate(c, postService, userClient)
 		})
 	}
 }
@@ -1,13 +1,16 @@
 package post
 
 import (
+	"errors"
 	"net/http"
 	"post/api/client"
 	"post/api/payload"
 	"post/api/presenter"
+	"post/entity"
 	"post/usecase/post"
 	"post/util"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,13 +18,20 @@ import (
 func GetPost(c *gin.Context, postService *post.Service, userClient client.UserClient) {
 	ctx := c.Request.Context()
 	postID := c.Param("postID")
-	postObj, err := postService.GetPost(ctx, postID)
+
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pPost, err := PostEntityToPresenterWithClient(postObj, userClient)
+	postObj, err := postService.GetPost(ctx, postID, viewer)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pPost, err := PostEntityToPresenterWithClient(c, postObj, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -44,13 +54,137 @@ func GetNearLostPosts(c *gin.Context, postService *post.Service, userClient clie
 	}
 	pagination := util.ExtractPagination(c)
 
-	posts, err := postService.GetNearLostPosts(ctx, lat, lng, pagination)
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := postService.GetNearLostPosts(ctx, lat, lng, viewer, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pPosts)
+}
+
+func GetFeed(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	ctx := c.Request.Context()
+
+	pagination, err := util.ExtractFeedPagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, nextCursor, err := postService.GetFeed(ctx, viewer, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
+		return
+	}
+
+	page := &presenter.FeedPage{Posts: pPosts}
+	if nextCursor != "" {
+		page.NextCursor = &nextCursor
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func GetPostsByTag(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	ctx := c.Request.Context()
+	pagination := util.ExtractPagination(c)
+
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := postService.GetPostsByTag(ctx, c.Param("tag"), viewer, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pPosts)
+}
+
+// GetTrendingTags reads the window in hours from `window`, one day by default and a week at most
+func GetTrendingTags(c *gin.Context, postService *post.Service) {
+	ctx := c.Request.Context()
+
+	window, err := strconv.ParseInt(c.Query("window"), 10, 64)
+	if err != nil || window <= 0 {
+		window = 24
+	}
+	if window > 7*24 {
+		window = 7 * 24
+	}
+	limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	tags, err := postService.GetTrendingTags(ctx, time.Duration(window)*time.Hour, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": presenter.ListTrendingTagEntityToPresenter(tags)})
+}
+
+func SearchPosts(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	ctx := c.Request.Context()
+	pagination := util.ExtractPagination(c)
+
+	search, err := ExtractPostSearch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pPosts, err := ListPostEntityToPresenterWithClient(posts, userClient)
+	posts, err := postService.SearchPosts(ctx, search, viewer, pagination)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -64,13 +198,19 @@ func GetPostsOfUser(c *gin.Context, postService *post.Service, userClient client
 	username := c.Param("username")
 	pagination := util.ExtractPagination(c)
 
-	posts, err := postService.GetPostsOfUser(ctx, username, pagination)
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := postService.GetPostsOfUser(ctx, username, viewer, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pPosts, err := ListPostEntityToPresenterWithClient(posts, userClient)
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -84,13 +224,19 @@ func GetParticipatedPostsOfUser(c *gin.Context, postService *post.Service, userC
 	username := c.Param("username")
 	pagination := util.ExtractPagination(c)
 
-	posts, err := postService.GetParticipatedPostsOfUser(ctx, username, pagination)
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := postService.GetParticipatedPostsOfUser(ctx, username, viewer, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pPosts, err := ListPostEntityToPresenterWithClient(posts, userClient)
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -109,13 +255,19 @@ func GetPostsOfUsers(c *gin.Context, postService *post.Service, userClient clien
 	ctx := c.Request.Context()
 	pagination := util.ExtractPagination(c)
 
-	posts, err := postService.GetPostsOfUsers(ctx, body.Usernames, pagination)
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := postService.GetPostsOfUsers(ctx, body.Usernames, viewer, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pPosts, err := ListPostEntityToPresenterWithClient(posts, userClient)
+	pPosts, err := ListPostEntityToPresenterWithClient(c, posts, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -132,9 +284,9 @@ func CreateBlogPost(c *gin.Context, postService *post.Service) {
 	}
 
 	ctx := c.Request.Context()
-	newPost, err := postService.CreateBlogPost(ctx, util.MustGetUsername(c), body.Content, body.Medias)
+	newPost, err := postService.CreateBlogPost(ctx, util.MustGetUsername(c), body.Content, body.Medias, body.Visibility, body.Audience, body.PetID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, newPost)
@@ -159,13 +311,16 @@ func CreateLostPetPost(c *gin.Context, postService *post.Service, userClient cli
 		presenter.LocationPresenterToEntity(&body.Area),
 		presenter.LocationPresenterToEntity(&body.LastSeen),
 		body.LostAt,
+		body.Visibility,
+		body.Audience,
+		body.PetID,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	pPost, err := PostEntityToPresenterWithClient(newPost, userClient)
+	pPost, err := PostEntityToPresenterWithClient(c, newPost, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting post: " + err.Error()})
 		return
@@ -193,8 +348,8 @@ func PatchContentPost(c *gin.Context, postService *post.Service) {
 		return
 	}
 
-	if postService.PatchContent(ctx, c.Param("postID"), body.Content, body.Medias) != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := postService.PatchContent(ctx, c.Param("postID"), username, body.Content, body.Medias); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -208,8 +363,10 @@ func PatchLostFoundStatus(c *gin.Context, postService *post.Service) {
 		return
 	}
 
+	username := util.MustGetUsername(c)
+
 	ctx := c.Request.Context()
-	isOwner, err := postService.CheckOwnership(ctx, util.MustGetUsername(c), c.Param("postID"))
+	isOwner, err := postService.CheckOwnership(ctx, username, c.Param("postID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -219,7 +376,7 @@ func PatchLostFoundStatus(c *gin.Context, postService *post.Service) {
 		return
 	}
 
-	err = postService.PatchLostFoundStatus(ctx, c.Param("postID"), body.IsResolved)
+	err = postService.PatchLostFoundStatus(ctx, c.Param("postID"), username, body.IsResolved)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -227,6 +384,33 @@ func PatchLostFoundStatus(c *gin.Context, postService *post.Service) {
 	c.Status(http.StatusOK)
 }
 
+func PatchVisibility(c *gin.Context, postService *post.Service) {
+	var body payload.PatchVisibilityPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	username := util.MustGetUsername(c)
+
+	ctx := c.Request.Context()
+	isOwner, err := postService.CheckOwnership(ctx, username, c.Param("postID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !isOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this post"})
+		return
+	}
+
+	err = postService.PatchVisibility(ctx, c.Param("postID"), username, body.Visibility, body.Audience)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
 func DeletePost(c *gin.Context, postService *post.Service) {
 	var body payload.DeletePostPayload
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -254,13 +438,21 @@ func DeletePost(c *gin.Context, postService *post.Service) {
 
 func GetComments(c *gin.Context, postService *post.Service, userClient client.UserClient) {
 	ctx := c.Request.Context()
-	comments, err := postService.GetComments(ctx, c.Param("postID"))
+	pagination := util.ExtractPagination(c)
+
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	pComments, err := ListCommentEntityToPresenterWithClient(comments, userClient)
+	comments, err := postService.GetComments(ctx, c.Param("postID"), viewer, pagination)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pComments, err := ListCommentEntityToPresenterWithClient(c, comments, postService, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting comments: " + err.Error()})
 		return
@@ -269,63 +461,222 @@ func GetComments(c *gin.Context, postService *post.Service, userClient client.Us
 	c.JSON(http.StatusOK, gin.H{"comments": pComments})
 }
 
-func CreateComment(c *gin.Context, postService *post.Service) {
+func GetReplies(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	ctx := c.Request.Context()
+	pagination := util.ExtractPagination(c)
+
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	replies, err := postService.GetReplies(ctx, c.Param("postID"), c.Param("commentID"), viewer, pagination)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pReplies, err := ListCommentEntityToPresenterWithClient(c, replies, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting comments: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": pReplies})
+}
+
+func CreateComment(c *gin.Context, postService *post.Service, userClient client.UserClient) {
 	var body payload.CreateCommentPayload
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	comment, err := postService.CreateComment(ctx, c.Param("postID"), body.ParentID, viewer, body.Content)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pComment, err := CommentEntityToPresenterWithClient(c, comment, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting comment: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, pComment)
+}
+
+func EditComment(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	var body payload.EditCommentPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx := c.Request.Context()
-	err := postService.CreateComment(ctx, c.Param("postID"), util.MustGetUsername(c), body.Content)
+	comment, err := postService.EditComment(ctx, c.Param("postID"), c.Param("commentID"), util.MustGetUsername(c), body.Content)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pComment, err := CommentEntityToPresenterWithClient(c, comment, postService, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting comment: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pComment)
+}
+
+func DeleteComment(c *gin.Context, postService *post.Service) {
+	ctx := c.Request.Context()
+	err := postService.DeleteComment(ctx, c.Param("postID"), c.Param("commentID"), util.MustGetUsername(c))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func UpsertCommentInteraction(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	var body payload.UpsertInteractionPayload
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	ctx := c.Request.Context()
+	err = postService.UpsertCommentInteraction(ctx, c.Param("postID"), c.Param("commentID"), viewer, body.Type)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.Status(http.StatusCreated)
 }
 
-func UpsertInteraction(c *gin.Context, postService *post.Service) {
+func DeleteCommentInteraction(c *gin.Context, postService *post.Service) {
+	ctx := c.Request.Context()
+	err := postService.DeleteCommentInteraction(ctx, c.Param("postID"), c.Param("commentID"), util.MustGetUsername(c))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, post.ErrEmptyComment), errors.Is(err, post.ErrReplyTooDeep), errors.Is(err, post.ErrInvalidInteraction),
+		errors.Is(err, post.ErrInvalidVisibility), errors.Is(err, post.ErrInvalidAudience), errors.Is(err, post.ErrInvalidSearch),
+		errors.Is(err, post.ErrInvalidPet):
+		return http.StatusBadRequest
+	case errors.Is(err, post.ErrNotCommentAuthor):
+		return http.StatusForbidden
+	case errors.Is(err, post.ErrPostNotFound), errors.Is(err, post.ErrCommentNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func UpsertInteraction(c *gin.Context, postService *post.Service, userClient client.UserClient) {
 	var body payload.UpsertInteractionPayload
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx := c.Request.Context()
-	err := postService.UpsertInteraction(ctx, c.Param("postID"), util.MustGetUsername(c), body.Type)
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	ctx := c.Request.Context()
+	err = postService.UpsertInteraction(ctx, c.Param("postID"), viewer, body.Type)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.Status(http.StatusCreated)
 }
 
 func DeleteInteraction(c *gin.Context, postService *post.Service) {
 	ctx := c.Request.Context()
-	if postService.DeleteInteraction(ctx, c.Param("postID"), util.MustGetUsername(c)) != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete interaction"})
+	err := postService.DeleteInteraction(ctx, c.Param("postID"), util.MustGetUsername(c))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
-func Participate(c *gin.Context, postService *post.Service) {
+func GetInteractions(c *gin.Context, postService *post.Service, userClient client.UserClient) {
 	ctx := c.Request.Context()
-	err := postService.Participate(ctx, c.Param("postID"), util.MustGetUsername(c))
+	pagination := util.ExtractPagination(c)
+
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.Status(http.StatusCreated)
+
+	interactions, err := postService.GetInteractions(ctx, c.Param("postID"), entity.InteractionType(c.Query("type")), viewer, pagination)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	pInteractions, err := ListInteractionEntityToPresenterWithClient(interactions, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error converting interactions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"interactions": pInteractions})
 }
 
-func Unparticipate(c *gin.Context, postService *post.Service) {
+func Participate(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	viewer, err := GetViewer(c, userClient)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx := c.Request.Context()
-	err := postService.Unparticipate(ctx, c.Param("postID"), util.MustGetUsername(c))
+	err = postService.Participate(ctx, c.Param("postID"), viewer)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func Unparticipate(c *gin.Context, postService *post.Service, userClient client.UserClient) {
+	viewer, err := GetViewer(c, userClient)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	ctx := c.Request.Context()
+	err = postService.Unparticipate(ctx, c.Param("postID"), viewer)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
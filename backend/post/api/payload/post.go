@@ -13,6 +13,13 @@ type UsersPostsRequest struct {
 type CreateBlogPostPayload struct {
 	Content string         `json:"content"`
 	Medias  []entity.Media `json:"medias"`
+
+	// Public when left empty, Audience is only read for custom visibility
+	Visibility entity.Visibility `json:"visibility"`
+	Audience   []string          `json:"audience"`
+
+	// One of the author's pets
+	PetID *uint `json:"petId"`
 }
 
 type CreateLostPetPostPayload struct {
@@ -24,6 +31,13 @@ type CreateLostPetPostPayload struct {
 	LostAt      *time.Time         `json:"lostAt"`
 	Area        presenter.Location `json:"area"`
 	LastSeen    presenter.Location `json:"lastSeen"`
+
+	// Public when left empty so the whole neighbourhood can help
+	Visibility entity.Visibility `json:"visibility"`
+	Audience   []string          `json:"audience"`
+
+	// One of the author's pets, its photos are used when Medias is empty
+	PetID *uint `json:"petId"`
 }
 
 type PatchContentPayload struct {
@@ -35,12 +49,23 @@ type PatchLostFoundStatus struct {
 	IsResolved bool `json:"isResolved"`
 }
 
+type PatchVisibilityPayload struct {
+	Visibility entity.Visibility `json:"visibility"`
+	Audience   []string          `json:"audience"`
+}
+
 type DeletePostPayload struct {
 	PostID string `json:"postId"`
 }
 
 type CreateCommentPayload struct {
 	Content string `json:"content"`
+	// Set to reply to another comment of the same post
+	ParentID string `json:"parentId"`
+}
+
+type EditCommentPayload struct {
+	Content string `json:"content"`
 }
 
 type UpsertInteractionPayload struct {
@@ -17,9 +17,20 @@ type Post struct {
 	Medias      []entity.Media     `json:"medias"`
 	CreatedAt   time.Time          `json:"createdAt"`
 	UpdatedAt   time.Time          `json:"updatedAt"`
+	// Nil when the post is not about a pet or its profile was deleted
+	Pet *Pet `json:"pet"`
 
-	Interactions []entity.Interaction `json:"interactions"`
-	CommentNum   int                  `json:"commentNum"`
+	Visibility entity.Visibility `json:"visibility"`
+	// Only shown to the post's owner
+	Audience []string `json:"audience,omitempty"`
+	Tags     []string `json:"tags"`
+	Mentions []string `json:"mentions"`
+
+	// Every interaction type is listed, ViewerInteraction is nil when the viewer did not react
+	InteractionCounts map[entity.InteractionType]int `json:"interactionCounts"`
+	InteractionNum    int                            `json:"interactionNum"`
+	ViewerInteraction *entity.InteractionType        `json:"viewerInteraction"`
+	CommentNum        int                            `json:"commentNum"`
 
 	// Optional: Lost Found Post
 	LostAt       *time.Time `json:"lostAt,omitempty"`
@@ -30,6 +41,25 @@ type Post struct {
 	Participants []string   `json:"participants,omitempty"`
 }
 
+type Interaction struct {
+	Username    string                 `json:"username"`
+	DisplayName string                 `json:"displayName"`
+	Avatar      string                 `json:"avatar"`
+	Type        entity.InteractionType `json:"type"`
+	CreatedAt   time.Time              `json:"createdAt"`
+}
+
+type FeedPage struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor *string `json:"nextCursor"`
+}
+
+type TrendingTag struct {
+	Tag       string `json:"tag"`
+	PostNum   int    `json:"postNum"`
+	AuthorNum int    `json:"authorNum"`
+}
+
 type Location struct {
 	Address string  `json:"address"`
 	Lat     float64 `json:"lat"`
@@ -37,11 +67,39 @@ type Location struct {
 }
 
 type Comment struct {
-	Username    string    `json:"username"`
-	DisplayName string    `json:"displayName"`
-	Avatar      string    `json:"avatar"`
-	Content     string    `json:"content"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID          primitive.ObjectID `json:"id"`
+	PostID      primitive.ObjectID `json:"postId"`
+	Username    string             `json:"username"`
+	DisplayName string             `json:"displayName"`
+	Avatar      string             `json:"avatar"`
+	Content     string             `json:"content"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	EditedAt    *time.Time         `json:"editedAt,omitempty"`
+
+	ParentID   *primitive.ObjectID `json:"parentId,omitempty"`
+	Depth      int                 `json:"depth"`
+	ReplyCount int                 `json:"replyCount"`
+
+	// Same as on posts: every interaction type is listed, ViewerInteraction is nil when the viewer did not react
+	InteractionCounts map[entity.InteractionType]int `json:"interactionCounts"`
+	InteractionNum    int                            `json:"interactionNum"`
+	ViewerInteraction *entity.InteractionType        `json:"viewerInteraction"`
+}
+
+func InteractionCountsEntityToPresenter(counts map[entity.InteractionType]int) map[entity.InteractionType]int {
+	out := make(map[entity.InteractionType]int, len(entity.InteractionTypes))
+	for _, itype := range entity.InteractionTypes {
+		out[itype] = counts[itype]
+	}
+	return out
+}
+
+func ListTrendingTagEntityToPresenter(tags []*entity.TrendingTag) []*TrendingTag {
+	pTags := make([]*TrendingTag, len(tags))
+	for i, t := range tags {
+		pTags[i] = &TrendingTag{Tag: t.Tag, PostNum: t.PostCount, AuthorNum: t.AuthorCount}
+	}
+	return pTags
 }
 
 func LocationEntityToPresenter(loc *entity.Location) *Location {
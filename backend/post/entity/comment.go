@@ -1,9 +1,33 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCommentDepth is how deep replies may nest, top-level comments are at depth 0
+const MaxCommentDepth = 2
 
 type Comment struct {
-	Username  string    `bson:"username" json:"username"`
-	Content   string    `bson:"content" json:"content"`
-	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	Username  string             `bson:"username" json:"username"`
+	Content   string             `bson:"content" json:"content"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	EditedAt  *time.Time         `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+
+	// Replies keep the IDs of every comment above them, the direct parent last, which lets a
+	// whole thread be removed at once
+	ParentID   *primitive.ObjectID  `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Ancestors  []primitive.ObjectID `bson:"ancestors,omitempty" json:"ancestors,omitempty"`
+	ReplyCount int                  `bson:"replyCount" json:"replyCount"`
+
+	// The reactions themselves are in their own collection, see CommentInteraction
+	InteractionCounts map[InteractionType]int `bson:"interactionCounts" json:"interactionCounts"`
+	InteractionCount  int                     `bson:"interactionCount" json:"interactionCount"`
+}
+
+func (c *Comment) Depth() int {
+	return len(c.Ancestors)
 }
@@ -1,13 +1,55 @@
 package entity
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type InteractionType string
 
 const (
 	Like  InteractionType = "like"
-	Heart                 = "heart"
+	Heart InteractionType = "heart"
+	Haha  InteractionType = "haha"
+	Wow   InteractionType = "wow"
+	Sad   InteractionType = "sad"
+	Angry InteractionType = "angry"
 )
 
+var InteractionTypes = []InteractionType{Like, Heart, Haha, Wow, Sad, Angry}
+
+func (t InteractionType) IsValid() bool {
+	for _, it := range InteractionTypes {
+		if t == it {
+			return true
+		}
+	}
+	return false
+}
+
 type Interaction struct {
 	Type     InteractionType `bson:"type" json:"type"`
 	Username string          `bson:"username" json:"username"`
 }
+
+// PostInteraction is a reaction to a post, kept in its own collection with at most one per user
+// and post. The post itself only stores the counters
+type PostInteraction struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	Username  string             `bson:"username" json:"username"`
+	Type      InteractionType    `bson:"type" json:"type"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// CommentInteraction is a reaction to a comment, at most one per user and comment. PostID lets
+// the reactions go together with the post
+type CommentInteraction struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CommentID primitive.ObjectID `bson:"commentId" json:"commentId"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	Username  string             `bson:"username" json:"username"`
+	Type      InteractionType    `bson:"type" json:"type"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -22,9 +22,23 @@ type Post struct {
 	Medias    []Media            `bson:"medias" json:"medias"`
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	// Profile of the author's pet the post is about, kept by the user service
+	PetID *uint `bson:"petId,omitempty" json:"petId,omitempty"`
 
-	Interactions []Interaction `bson:"interactions" json:"interactions"`
-	Comments     []Comment     `bson:"comments" json:"comments,omitempty"`
+	Visibility Visibility `bson:"visibility" json:"visibility"`
+	// Who can read a custom visibility post besides the author
+	Audience []string `bson:"audience,omitempty" json:"audience,omitempty"`
+
+	// Parsed out of Content whenever it is written, lowercased tags without the # and mentioned
+	// usernames without the @
+	Tags     []string `bson:"tags" json:"tags"`
+	Mentions []string `bson:"mentions" json:"mentions"`
+
+	// Updated together with the interaction collection, one counter per type plus their total
+	InteractionCounts map[InteractionType]int `bson:"interactionCounts" json:"interactionCounts"`
+	InteractionCount  int                     `bson:"interactionCount" json:"interactionCount"`
+	// Kept in step with the comment collection so lists do not have to count
+	CommentCount int `bson:"commentCount" json:"commentCount"`
 
 	// Optional: Lost Found Post
 	LostAt       *time.Time `bson:"lostAt,omitempty" json:"lostAt,omitempty"`
@@ -44,3 +58,9 @@ type Location struct {
 	Location GeoPoint `bson:"location" json:"location"`
 	Address  string   `bson:"address" json:"address"`
 }
+
+// RankedPost is a post of the home feed along with the score it was ranked by
+type RankedPost struct {
+	Post  `bson:",inline"`
+	Score float64 `bson:"score"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+type SearchSort string
+
+const (
+	// Best text match first, newest first among equal matches
+	SortRelevance SearchSort = "relevance"
+	SortRecent    SearchSort = "recent"
+)
+
+// PostSearch narrows down posts by the words in their content, contact info and last seen address,
+// every other field is a filter that only applies when set
+type PostSearch struct {
+	Query    string
+	Type     PostType
+	Resolved *bool
+	From     *time.Time
+	To       *time.Time
+	Author   string
+	Sort     SearchSort
+}
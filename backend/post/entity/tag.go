@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"regexp"
+	"strings"
+)
+
+const MaxTagLength = 50
+
+// A tag or mention starts a word, so emails and anchors like a#b are left alone
+var (
+	tagPattern     = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&])#([\p{L}\p{N}_]+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_.]+)`)
+)
+
+// TrendingTag is how many posts used the tag within a time window, and how many users wrote them
+type TrendingTag struct {
+	Tag         string `bson:"_id"`
+	PostCount   int    `bson:"postCount"`
+	AuthorCount int    `bson:"authorCount"`
+}
+
+// ParseTags lists the hashtags of the content lowercased and without the leading #, each once
+func ParseTags(content string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, match := range tagPattern.FindAllStringSubmatch(content, -1) {
+		tag := NormalizeTag(match[1])
+		if tag == "" || len([]rune(tag)) > MaxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// ParseMentions lists the usernames mentioned in the content without the leading @, each once.
+// They are not checked against the user service
+func ParseMentions(content string) []string {
+	var mentions []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.TrimRight(match[1], ".")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		mentions = append(mentions, username)
+	}
+	return mentions
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"#Cats and #dogs, more #CATS", []string{"cats", "dogs"}},
+		{"found near the park #lostcat.", []string{"lostcat"}},
+		{"(#pets) #café #Straße", []string{"pets", "café", "straße"}},
+		{"anchor a#b and page.html#top", nil},
+		{"&#35; is not a tag", nil},
+		{"##double and # alone", nil},
+		{"#" + strings.Repeat("a", MaxTagLength+1) + " #ok", []string{"ok"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := ParseTags(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"thanks @alice, @bob_1 and @alice", []string{"alice", "bob_1"}},
+		{"ask @bob.", []string{"bob"}},
+		{"ask @jane.doe...", []string{"jane.doe"}},
+		{"mail me at me@example.com", nil},
+		{"twice @@bob", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"#Cats", "cats"},
+		{"  dogs ", "dogs"},
+		{" #LostCat", "lostcat"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.tag); got != tt.want {
+			t.Fatalf("NormalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
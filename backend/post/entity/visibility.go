@@ -0,0 +1,30 @@
+package entity
+
+// Visibility decides who besides the author can read a post
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityFriends Visibility = "friends"
+	VisibilityOnlyMe  Visibility = "onlyMe"
+	// Only the users listed in the post's audience
+	VisibilityCustom Visibility = "custom"
+)
+
+const MaxAudienceSize = 200
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityFriends, VisibilityOnlyMe, VisibilityCustom:
+		return true
+	}
+	return false
+}
+
+// Viewer is who reads posts. Their friends open up friends-only posts, and Hidden are the users
+// in a block relation with them. The zero value is an anonymous viewer, who only sees public posts
+type Viewer struct {
+	Username string
+	Friends  []string
+	Hidden   []string
+}
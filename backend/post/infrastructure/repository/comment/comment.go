@@ -0,0 +1,377 @@
+package comment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"post/entity"
+	"post/util"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository struct {
+	commentCollection     *mongo.Collection
+	interactionCollection *mongo.Collection
+	postCollection        *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) *Repository {
+	repo := &Repository{
+		commentCollection:     db.Collection("comment"),
+		interactionCollection: db.Collection("commentInteraction"),
+		postCollection:        db.Collection("post"),
+	}
+	repo.ensureIndexes()
+	repo.migrateEmbeddedComments()
+	return repo
+}
+
+func (r *Repository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.commentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("post_parent_created_index"),
+		},
+		{
+			Keys:    bson.D{{Key: "ancestors", Value: 1}},
+			Options: options.Index().SetName("ancestors_index"),
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create comment indexes: %v", err)
+	}
+
+	_, err = r.interactionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "commentId", Value: 1}, {Key: "username", Value: 1}},
+			Options: options.Index().SetName("comment_username_index").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "postId", Value: 1}},
+			Options: options.Index().SetName("post_index"),
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create comment interaction indexes: %v", err)
+	}
+}
+
+// migrateEmbeddedComments moves the comments still embedded in post documents into the comment
+// collection and sets the post's counter. Comments are upserted under IDs derived from the post and
+// their position, and a post is only unset after they are all written, so an interrupted run is
+// picked up again on the next start without duplicating or touching any other comment
+func (r *Repository) migrateEmbeddedComments() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := r.postCollection.Find(ctx, bson.M{"comments": bson.M{"$exists": true}}, options.Find().SetProjection(bson.M{"comments": 1}))
+	if err != nil {
+		fmt.Printf("Failed to find embedded comments: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Comments []struct {
+				Username  string    `bson:"username"`
+				Content   string    `bson:"content"`
+				CreatedAt time.Time `bson:"createdAt"`
+			} `bson:"comments"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			fmt.Printf("Failed to decode embedded comments: %v", err)
+			continue
+		}
+
+		if len(legacy.Comments) > 0 {
+			models := make([]mongo.WriteModel, len(legacy.Comments))
+			for i, c := range legacy.Comments {
+				id := legacyCommentID(legacy.ID, i, c.CreatedAt)
+				models[i] = mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"_id": id}).
+					SetUpdate(bson.M{"$setOnInsert": entity.Comment{
+						ID:        id,
+						PostID:    legacy.ID,
+						Username:  c.Username,
+						Content:   c.Content,
+						CreatedAt: c.CreatedAt,
+					}}).
+					SetUpsert(true)
+			}
+			if _, err := r.commentCollection.BulkWrite(ctx, models); err != nil {
+				fmt.Printf("Failed to migrate comments of post %s: %v", legacy.ID.Hex(), err)
+				continue
+			}
+		}
+
+		_, err := r.postCollection.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{
+			"$set":   bson.M{"commentCount": len(legacy.Comments)},
+			"$unset": bson.M{"comments": ""},
+		})
+		if err != nil {
+			fmt.Printf("Failed to migrate comments of post %s: %v", legacy.ID.Hex(), err)
+			continue
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		fmt.Printf("Migrated embedded comments of %d posts\n", migrated)
+	}
+}
+
+// legacyCommentID derives the ID of the index-th embedded comment of a post, keeping the comment's
+// creation time in the leading bytes like any other ObjectID
+func legacyCommentID(postID primitive.ObjectID, index int, createdAt time.Time) primitive.ObjectID {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", postID.Hex(), index)))
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[:4], uint32(createdAt.Unix()))
+	copy(id[4:], sum[:8])
+	return id
+}
+
+// GetComments pages through the top-level comments of a post oldest-first, or through the direct
+// replies of parentID when it is given, leaving out the hidden authors
+func (r *Repository) GetComments(ctx context.Context, postID string, parentID *primitive.ObjectID, hidden []string, pagination util.Pagination) ([]*entity.Comment, error) {
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A null match also finds the comments migrated from posts, which have no parentId at all
+	filter := bson.M{"postId": postOID, "parentId": nil}
+	if parentID != nil {
+		filter["parentId"] = *parentID
+	}
+	if len(hidden) > 0 {
+		filter["username"] = bson.M{"$nin": hidden}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(pagination.Offset()).
+		SetLimit(pagination.Size)
+
+	cursor, err := r.commentCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []*entity.Comment{}
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (r *Repository) GetComment(ctx context.Context, commentID string) (*entity.Comment, error) {
+	commentOID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment entity.Comment
+	err = r.commentCollection.FindOne(ctx, bson.M{"_id": commentOID}).Decode(&comment)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// CreateComment adds a top-level comment when parent is nil, and a reply to parent otherwise
+func (r *Repository) CreateComment(ctx context.Context, postID primitive.ObjectID, parent *entity.Comment, username, content string) (*entity.Comment, error) {
+	comment := entity.Comment{
+		ID:        primitive.NewObjectID(),
+		PostID:    postID,
+		Username:  username,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if parent != nil {
+		comment.ParentID = &parent.ID
+		comment.Ancestors = append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID)
+	}
+
+	if _, err := r.commentCollection.InsertOne(ctx, comment); err != nil {
+		return nil, err
+	}
+	if parent != nil {
+		if err := r.incReplyCount(ctx, parent.ID, 1); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.incCommentCount(ctx, postID, 1); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func (r *Repository) UpdateComment(ctx context.Context, comment *entity.Comment, content string) (*entity.Comment, error) {
+	now := time.Now()
+	_, err := r.commentCollection.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{
+		"$set": bson.M{
+			"content":  content,
+			"editedAt": now,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Content = content
+	comment.EditedAt = &now
+	return comment, nil
+}
+
+// DeleteComment removes the comment together with every reply below it and their reactions
+func (r *Repository) DeleteComment(ctx context.Context, comment *entity.Comment) error {
+	cursor, err := r.commentCollection.Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"_id": comment.ID},
+			bson.M{"ancestors": comment.ID},
+		},
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var thread []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &thread); err != nil {
+		return err
+	}
+	ids := make([]primitive.ObjectID, len(thread))
+	for i, c := range thread {
+		ids[i] = c.ID
+	}
+
+	result, err := r.commentCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return nil
+	}
+	if _, err := r.interactionCollection.DeleteMany(ctx, bson.M{"commentId": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+	if comment.ParentID != nil {
+		if err := r.incReplyCount(ctx, *comment.ParentID, -1); err != nil {
+			return err
+		}
+	}
+	return r.incCommentCount(ctx, comment.PostID, -int(result.DeletedCount))
+}
+
+func (r *Repository) DeleteCommentsOfPost(ctx context.Context, postID string) error {
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return err
+	}
+	if _, err := r.commentCollection.DeleteMany(ctx, bson.M{"postId": postOID}); err != nil {
+		return err
+	}
+	_, err = r.interactionCollection.DeleteMany(ctx, bson.M{"postId": postOID})
+	return err
+}
+
+// Interaction
+
+// UpsertInteraction sets the user's reaction to the comment and moves the comment's counters by
+// exactly the change it made, so concurrent reactions cannot drift them
+func (r *Repository) UpsertInteraction(ctx context.Context, comment *entity.Comment, username string, itype entity.InteractionType) error {
+	var previous entity.CommentInteraction
+	err := r.interactionCollection.FindOneAndUpdate(ctx,
+		bson.M{"commentId": comment.ID, "username": username},
+		bson.M{
+			"$set":         bson.M{"type": itype},
+			"$setOnInsert": bson.M{"postId": comment.PostID, "createdAt": time.Now()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	inc := bson.M{"interactionCounts." + string(itype): 1}
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		inc["interactionCount"] = 1
+	case err != nil:
+		return err
+	case previous.Type == itype:
+		return nil
+	default:
+		inc["interactionCounts."+string(previous.Type)] = -1
+	}
+
+	_, err = r.commentCollection.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$inc": inc})
+	return err
+}
+
+func (r *Repository) DeleteInteraction(ctx context.Context, commentID primitive.ObjectID, username string) error {
+	var previous entity.CommentInteraction
+	err := r.interactionCollection.FindOneAndDelete(ctx, bson.M{"commentId": commentID, "username": username}).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.commentCollection.UpdateOne(ctx, bson.M{"_id": commentID}, bson.M{
+		"$inc": bson.M{
+			"interactionCounts." + string(previous.Type): -1,
+			"interactionCount":                           -1,
+		},
+	})
+	return err
+}
+
+// GetInteractionsOfUser finds the reactions the user left on any of the given comments
+func (r *Repository) GetInteractionsOfUser(ctx context.Context, username string, commentIDs []primitive.ObjectID) ([]*entity.CommentInteraction, error) {
+	if len(commentIDs) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.interactionCollection.Find(ctx, bson.M{"username": username, "commentId": bson.M{"$in": commentIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var interactions []*entity.CommentInteraction
+	if err = cursor.All(ctx, &interactions); err != nil {
+		return nil, err
+	}
+
+	return interactions, nil
+}
+
+func (r *Repository) incReplyCount(ctx context.Context, commentID primitive.ObjectID, delta int) error {
+	_, err := r.commentCollection.UpdateOne(ctx, bson.M{"_id": commentID}, bson.M{
+		"$inc": bson.M{"replyCount": delta},
+	})
+	return err
+}
+
+func (r *Repository) incCommentCount(ctx context.Context, postID primitive.ObjectID, delta int) error {
+	_, err := r.postCollection.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{
+		"$inc": bson.M{"commentCount": delta},
+	})
+	return err
+}
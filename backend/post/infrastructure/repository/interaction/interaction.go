@@ -0,0 +1,214 @@
+package interaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"post/entity"
+	"post/util"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository struct {
+	interactionCollection *mongo.Collection
+	postCollection        *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) *Repository {
+	repo := &Repository{
+		interactionCollection: db.Collection("interaction"),
+		postCollection:        db.Collection("post"),
+	}
+	repo.ensureIndexes()
+	repo.migrateEmbeddedInteractions()
+	return repo
+}
+
+func (r *Repository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.interactionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "username", Value: 1}},
+			Options: options.Index().SetName("post_username_index").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "postId", Value: 1}, {Key: "type", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("post_type_created_index"),
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create interaction indexes: %v", err)
+	}
+}
+
+// migrateEmbeddedInteractions moves the interactions still embedded in post documents into the
+// interaction collection and counts them on the post. Inserts are upserts on (postId, username),
+// so an interrupted run is picked up again on the next start
+func (r *Repository) migrateEmbeddedInteractions() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := r.postCollection.Find(ctx, bson.M{"interactions": bson.M{"$exists": true}}, options.Find().SetProjection(bson.M{"interactions": 1, "createdAt": 1}))
+	if err != nil {
+		fmt.Printf("Failed to find embedded interactions: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID           primitive.ObjectID   `bson:"_id"`
+			CreatedAt    time.Time            `bson:"createdAt"`
+			Interactions []entity.Interaction `bson:"interactions"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			fmt.Printf("Failed to decode embedded interactions: %v", err)
+			continue
+		}
+
+		counts := map[entity.InteractionType]int{}
+		models := make([]mongo.WriteModel, 0, len(legacy.Interactions))
+		for _, i := range legacy.Interactions {
+			counts[i.Type]++
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"postId": legacy.ID, "username": i.Username}).
+				SetUpdate(bson.M{"$set": bson.M{"type": i.Type}, "$setOnInsert": bson.M{"createdAt": legacy.CreatedAt}}).
+				SetUpsert(true))
+		}
+		if len(models) > 0 {
+			if _, err := r.interactionCollection.BulkWrite(ctx, models); err != nil {
+				fmt.Printf("Failed to migrate interactions of post %s: %v", legacy.ID.Hex(), err)
+				continue
+			}
+		}
+
+		_, err := r.postCollection.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{
+			"$set":   bson.M{"interactionCounts": counts, "interactionCount": len(legacy.Interactions)},
+			"$unset": bson.M{"interactions": ""},
+		})
+		if err != nil {
+			fmt.Printf("Failed to migrate interactions of post %s: %v", legacy.ID.Hex(), err)
+			continue
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		fmt.Printf("Migrated embedded interactions of %d posts\n", migrated)
+	}
+}
+
+// UpsertInteraction sets the user's reaction to the post and moves the post's counters by exactly
+// the change it made, so concurrent reactions cannot drift them
+func (r *Repository) UpsertInteraction(ctx context.Context, postID primitive.ObjectID, username string, itype entity.InteractionType) error {
+	var previous entity.PostInteraction
+	err := r.interactionCollection.FindOneAndUpdate(ctx,
+		bson.M{"postId": postID, "username": username},
+		bson.M{
+			"$set":         bson.M{"type": itype},
+			"$setOnInsert": bson.M{"createdAt": time.Now()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	inc := bson.M{"interactionCounts." + string(itype): 1}
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		inc["interactionCount"] = 1
+	case err != nil:
+		return err
+	case previous.Type == itype:
+		return nil
+	default:
+		inc["interactionCounts."+string(previous.Type)] = -1
+	}
+
+	_, err = r.postCollection.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$inc": inc})
+	return err
+}
+
+func (r *Repository) DeleteInteraction(ctx context.Context, postID primitive.ObjectID, username string) error {
+	var previous entity.PostInteraction
+	err := r.interactionCollection.FindOneAndDelete(ctx, bson.M{"postId": postID, "username": username}).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.postCollection.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{
+		"$inc": bson.M{
+			"interactionCounts." + string(previous.Type): -1,
+			"interactionCount":                           -1,
+		},
+	})
+	return err
+}
+
+// GetInteractions pages through the reactions to a post newest-first, only those of itype when it
+// is not empty, leaving out the hidden users
+func (r *Repository) GetInteractions(ctx context.Context, postID primitive.ObjectID, itype entity.InteractionType, hidden []string, pagination util.Pagination) ([]*entity.PostInteraction, error) {
+	filter := bson.M{"postId": postID}
+	if itype != "" {
+		filter["type"] = itype
+	}
+	if len(hidden) > 0 {
+		filter["username"] = bson.M{"$nin": hidden}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(pagination.Offset()).
+		SetLimit(pagination.Size)
+
+	cursor, err := r.interactionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	interactions := []*entity.PostInteraction{}
+	if err = cursor.All(ctx, &interactions); err != nil {
+		return nil, err
+	}
+
+	return interactions, nil
+}
+
+// GetInteractionsOfUser finds the reactions the user left on any of the given posts
+func (r *Repository) GetInteractionsOfUser(ctx context.Context, username string, postIDs []primitive.ObjectID) ([]*entity.PostInteraction, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.interactionCollection.Find(ctx, bson.M{"username": username, "postId": bson.M{"$in": postIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var interactions []*entity.PostInteraction
+	if err = cursor.All(ctx, &interactions); err != nil {
+		return nil, err
+	}
+
+	return interactions, nil
+}
+
+func (r *Repository) DeleteInteractionsOfPost(ctx context.Context, postID string) error {
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return err
+	}
+	_, err = r.interactionCollection.DeleteMany(ctx, bson.M{"postId": postOID})
+	return err
+}
@@ -21,6 +21,8 @@ type Repository struct {
 func NewRepository(db *mongo.Database) *Repository {
 	repo := &Repository{postCollection: db.Collection("post")}
 	repo.ensureIndexes()
+	repo.migrateVisibility()
+	repo.migrateTags()
 	return repo
 }
 
@@ -41,17 +43,110 @@ func (r *Repository) ensureIndexes() {
 	} else {
 		fmt.Println("2dsphere index created successfully on lastSeen.location")
 	}
+
+	_, err = r.postCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}, {Key: "createdAt", Value: -1}},
+		Options: options.Index().SetName("username_created_index"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to create username index: %v", err)
+	}
+
+	_, err = r.postCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "content", Value: "text"},
+			{Key: "contactInfo", Value: "text"},
+			{Key: "lastSeen.address", Value: "text"},
+		},
+		Options: options.Index().SetName("post_text_index"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to create text index: %v", err)
+	}
+
+	_, err = r.postCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tags", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("tags_created_index"),
+		},
+		{
+			Keys:    bson.D{{Key: "mentions", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetName("mentions_created_index"),
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create tag indexes: %v", err)
+	}
+}
+
+// migrateVisibility makes the posts written before visibility existed public, as they always were
+func (r *Repository) migrateVisibility() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	result, err := r.postCollection.UpdateMany(ctx,
+		bson.M{"visibility": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"visibility": entity.VisibilityPublic}},
+	)
+	if err != nil {
+		fmt.Printf("Failed to migrate post visibility: %v", err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		fmt.Printf("Made %d legacy posts public\n", result.ModifiedCount)
+	}
 }
 
-func (p *Repository) GetPost(ctx context.Context, id string) (*entity.Post, error) {
+// migrateTags parses the tags and mentions of the posts written before they were kept. Mentions
+// found this way are not notified
+func (r *Repository) migrateTags() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cursor, err := r.postCollection.Find(ctx, bson.M{"tags": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{"content": 1}))
+	if err != nil {
+		fmt.Printf("Failed to find untagged posts: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Content string             `bson:"content"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			fmt.Printf("Failed to decode untagged post: %v", err)
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": legacy.ID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"tags":     entity.ParseTags(legacy.Content),
+				"mentions": entity.ParseMentions(legacy.Content),
+			}}))
+	}
+	if len(models) == 0 {
+		return
+	}
+
+	if _, err := r.postCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		fmt.Printf("Failed to migrate post tags: %v", err)
+		return
+	}
+	fmt.Printf("Parsed tags of %d legacy posts\n", len(models))
+}
+
+// GetPost finds the post only when the viewer is allowed to see it, otherwise it reports mongo.ErrNoDocuments
+func (p *Repository) GetPost(ctx context.Context, id string, viewer entity.Viewer) (*entity.Post, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var post entity.Post
-	opt := options.FindOne().SetProjection(bson.M{"comments": 0})
-	err = p.postCollection.FindOne(ctx, bson.M{"_id": objectID}, opt).Decode(&post)
+	err = p.postCollection.FindOne(ctx, visibleTo(bson.M{"_id": objectID}, viewer)).Decode(&post)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +154,7 @@ func (p *Repository) GetPost(ctx context.Context, id string) (*entity.Post, erro
 	return &post, nil
 }
 
-func (p *Repository) GetNearLostPosts(ctx context.Context, latitude float64, longitude float64, pagination util.Pagination) ([]*entity.Post, error) {
+func (p *Repository) GetNearLostPosts(ctx context.Context, latitude float64, longitude float64, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
 	var posts []*entity.Post
 	filter := bson.M{
 		"type": bson.M{
@@ -74,6 +169,7 @@ func (p *Repository) GetNearLostPosts(ctx context.Context, latitude float64, lon
 			},
 		},
 	}
+	visibleTo(filter, viewer)
 
 	opts := options.Find().
 		SetSkip(pagination.Offset()).
@@ -92,10 +188,27 @@ func (p *Repository) GetNearLostPosts(ctx context.Context, latitude float64, lon
 	return posts, nil
 }
 
-func (p *Repository) GetParticipatedPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error) {
+func (p *Repository) GetParticipatedPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	var posts []*entity.Post
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(pagination.Offset()).SetLimit(pagination.Size)
+	cursor, err := p.postCollection.Find(ctx, visibleTo(bson.M{"participants": username}, viewer), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (p *Repository) GetPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
 	var posts []*entity.Post
+
 	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(pagination.Offset()).SetLimit(pagination.Size)
-	cursor, err := p.postCollection.Find(ctx, bson.M{"participants": username}, opts)
+	cursor, err := p.postCollection.Find(ctx, visibleTo(bson.M{"username": username}, viewer), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -108,16 +221,75 @@ func (p *Repository) GetParticipatedPostsOfUser(ctx context.Context, username st
 	return posts, nil
 }
 
-func (p *Repository) GetPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error) {
+func (p *Repository) GetPostsOfUsers(ctx context.Context, usernames []string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
 	var posts []*entity.Post
 
 	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(pagination.Offset()).SetLimit(pagination.Size)
-	cursor, err := p.postCollection.Find(ctx, bson.M{"username": username}, opts)
+	cursor, err := p.postCollection.Find(ctx, visibleTo(bson.M{"username": bson.M{"$in": usernames}}, viewer), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Feed ranking: engagement counts one per interaction and two per comment, and decays with the
+// post's age in hours the same way as on news aggregators
+const (
+	feedCommentWeight = 2
+	feedAgeOffset     = 2
+	feedGravity       = 1.5
+)
+
+// GetFeed ranks the posts of the given authors that the viewer can see by engagement and recency as
+// of pagination.AsOf, highest score first. Posts created after AsOf are left for the next fresh read
+func (p *Repository) GetFeed(ctx context.Context, authors []string, viewer entity.Viewer, pagination util.FeedPagination) ([]*entity.RankedPost, error) {
+	match := visibleTo(bson.M{
+		"username":  bson.M{"$in": authors},
+		"createdAt": bson.M{"$lte": pagination.AsOf},
+	}, viewer)
+
+	ageHours := bson.M{"$max": bson.A{0, bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{pagination.AsOf, "$createdAt"}}, 3600000}}}}
+	engagement := bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$interactionCount", 0}},
+		bson.M{"$multiply": bson.A{feedCommentWeight, bson.M{"$ifNull": bson.A{"$commentCount", 0}}}},
+	}}
+	score := bson.M{"$divide": bson.A{
+		bson.M{"$add": bson.A{1, engagement}},
+		bson.M{"$pow": bson.A{bson.M{"$add": bson.A{ageHours, feedAgeOffset}}, feedGravity}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{"score": score}}},
+	}
+	if c := pagination.Cursor; c != nil {
+		id, err := primitive.ObjectIDFromHex(c.ID)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"score": bson.M{"$lt": c.Score}},
+			bson.M{"score": c.Score, "_id": bson.M{"$lt": id}},
+		}}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: pagination.Size + 1}},
+	)
+
+	cursor, err := p.postCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
+	var posts []*entity.RankedPost
 	if err = cursor.All(ctx, &posts); err != nil {
 		return nil, err
 	}
@@ -125,11 +297,11 @@ func (p *Repository) GetPostsOfUser(ctx context.Context, username string, pagina
 	return posts, nil
 }
 
-func (p *Repository) GetPostsOfUsers(ctx context.Context, usernames []string, pagination util.Pagination) ([]*entity.Post, error) {
+func (p *Repository) GetPostsByTag(ctx context.Context, tag string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
 	var posts []*entity.Post
 
 	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(pagination.Offset()).SetLimit(pagination.Size)
-	cursor, err := p.postCollection.Find(ctx, bson.M{"username": bson.M{"$in": usernames}}, opts)
+	cursor, err := p.postCollection.Find(ctx, visibleTo(bson.M{"tags": tag}, viewer), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +314,133 @@ func (p *Repository) GetPostsOfUsers(ctx context.Context, usernames []string, pa
 	return posts, nil
 }
 
+// GetTrendingTags counts the tags of the public posts created since the given time. Tags used by
+// more users come first, so one user posting a tag over and over cannot push it up alone
+func (p *Repository) GetTrendingTags(ctx context.Context, since time.Time, limit int64) ([]*entity.TrendingTag, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"createdAt":  bson.M{"$gte": since},
+			"visibility": entity.VisibilityPublic,
+			"tags.0":     bson.M{"$exists": true},
+		}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$tags",
+			"postCount": bson.M{"$sum": 1},
+			"authors":   bson.M{"$addToSet": "$username"},
+		}}},
+		{{Key: "$project", Value: bson.M{"postCount": 1, "authorCount": bson.M{"$size": "$authors"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "authorCount", Value: -1}, {Key: "postCount", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := p.postCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tags []*entity.TrendingTag
+	if err = cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SearchPosts finds the posts the viewer can see that match the search. Without a query the
+// filters alone apply and posts always come newest first
+func (p *Repository) SearchPosts(ctx context.Context, search entity.PostSearch, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	filter := bson.M{}
+	if search.Query != "" {
+		filter["$text"] = bson.M{"$search": search.Query}
+	}
+	if search.Type != "" {
+		filter["type"] = search.Type
+	}
+	if search.Resolved != nil {
+		// Posts that were never resolved have no isResolved field at all
+		if *search.Resolved {
+			filter["isResolved"] = true
+		} else {
+			filter["isResolved"] = bson.M{"$ne": true}
+		}
+	}
+	if search.From != nil || search.To != nil {
+		createdAt := bson.M{}
+		if search.From != nil {
+			createdAt["$gte"] = *search.From
+		}
+		if search.To != nil {
+			createdAt["$lte"] = *search.To
+		}
+		filter["createdAt"] = createdAt
+	}
+	if search.Author != "" {
+		filter["username"] = search.Author
+	}
+	visibleTo(filter, viewer)
+
+	opts := options.Find().SetSkip(pagination.Offset()).SetLimit(pagination.Size)
+	if search.Query != "" && search.Sort == entity.SortRelevance {
+		textScore := bson.M{"$meta": "textScore"}
+		opts.SetProjection(bson.M{"score": textScore}).
+			SetSort(bson.D{{Key: "score", Value: textScore}, {Key: "createdAt", Value: -1}})
+	} else {
+		opts.SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	}
+
+	cursor, err := p.postCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*entity.Post
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// visibleTo narrows the filter to the posts the viewer is allowed to see: public ones, their own,
+// friends-only ones of their friends and custom ones they are in the audience of. Posts of the
+// viewer's hidden users are left out whatever their visibility
+func visibleTo(filter bson.M, viewer entity.Viewer) bson.M {
+	hideAuthors(filter, viewer.Hidden)
+
+	visible := bson.A{bson.M{"visibility": entity.VisibilityPublic}}
+	if viewer.Username != "" {
+		visible = append(visible,
+			bson.M{"username": viewer.Username},
+			bson.M{"visibility": entity.VisibilityCustom, "audience": viewer.Username},
+		)
+		if len(viewer.Friends) > 0 {
+			visible = append(visible, bson.M{"visibility": entity.VisibilityFriends, "username": bson.M{"$in": viewer.Friends}})
+		}
+	}
+	filter["$or"] = visible
+	return filter
+}
+
+// hideAuthors narrows the filter to posts whose author is not in hidden, keeping any existing username condition
+func hideAuthors(filter bson.M, hidden []string) bson.M {
+	if len(hidden) == 0 {
+		return filter
+	}
+	cond, ok := filter["username"].(bson.M)
+	if !ok {
+		cond = bson.M{}
+		if username, exists := filter["username"]; exists {
+			cond["$eq"] = username
+		}
+	}
+	cond["$nin"] = hidden
+	filter["username"] = cond
+	return filter
+}
+
 func (p *Repository) GetMediasOfPost(ctx context.Context, postID string) ([]entity.Media, error) {
 	objectID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
@@ -169,17 +468,21 @@ func (p *Repository) CheckOwnership(ctx context.Context, postID string, username
 	return count > 0, nil
 }
 
-func (p *Repository) CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media) (*entity.Post, error) {
+func (p *Repository) CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error) {
 	post := entity.Post{
-		ID:           primitive.NewObjectID(),
-		Username:     username,
-		Type:         entity.Blog,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-		Comments:     []entity.Comment{},
-		Interactions: []entity.Interaction{},
-		Content:      content,
-		Medias:       medias,
+		ID:                primitive.NewObjectID(),
+		Username:          username,
+		Type:              entity.Blog,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+		PetID:             petID,
+		InteractionCounts: map[entity.InteractionType]int{},
+		Content:           content,
+		Medias:            medias,
+		Visibility:        visibility,
+		Audience:          audience,
+		Tags:              entity.ParseTags(content),
+		Mentions:          entity.ParseMentions(content),
 	}
 
 	_, err := p.postCollection.InsertOne(ctx, post)
@@ -190,19 +493,23 @@ func (p *Repository) CreateBlogPost(ctx context.Context, username, content strin
 	return &post, nil
 }
 
-func (p *Repository) CreateLostPetPost(ctx context.Context, username string, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time) (*entity.Post, error) {
+func (p *Repository) CreateLostPetPost(ctx context.Context, username string, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error) {
 	var post entity.Post
 	if postType == entity.Found {
 		post = entity.Post{
-			ID:           primitive.NewObjectID(),
-			Username:     username,
-			Type:         postType,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-			Comments:     []entity.Comment{},
-			Interactions: []entity.Interaction{},
-			Content:      content,
-			Medias:       medias,
+			ID:                primitive.NewObjectID(),
+			Username:          username,
+			Type:              postType,
+			CreatedAt:         time.Now(),
+			UpdatedAt:         time.Now(),
+			PetID:             petID,
+			InteractionCounts: map[entity.InteractionType]int{},
+			Content:           content,
+			Medias:            medias,
+			Visibility:        visibility,
+			Audience:          audience,
+			Tags:              entity.ParseTags(content),
+			Mentions:          entity.ParseMentions(content),
 
 			Participants: []string{},
 			ContactInfo:  contactInfo,
@@ -212,15 +519,19 @@ func (p *Repository) CreateLostPetPost(ctx context.Context, username string, con
 		}
 	} else {
 		post = entity.Post{
-			ID:           primitive.NewObjectID(),
-			Username:     username,
-			Type:         postType,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-			Comments:     []entity.Comment{},
-			Interactions: []entity.Interaction{},
-			Content:      content,
-			Medias:       medias,
+			ID:                primitive.NewObjectID(),
+			Username:          username,
+			Type:              postType,
+			CreatedAt:         time.Now(),
+			UpdatedAt:         time.Now(),
+			PetID:             petID,
+			InteractionCounts: map[entity.InteractionType]int{},
+			Content:           content,
+			Medias:            medias,
+			Visibility:        visibility,
+			Audience:          audience,
+			Tags:              entity.ParseTags(content),
+			Mentions:          entity.ParseMentions(content),
 
 			Participants: []string{},
 			ContactInfo:  contactInfo,
@@ -248,9 +559,11 @@ func (p *Repository) PatchContent(ctx context.Context, id, content string, media
 	result, err := p.postCollection.UpdateOne(ctx, bson.M{"_id": objectID},
 		bson.M{
 			"$set": bson.M{
-				"content":    content,
-				"medias":     medias,
-				"updated_at": time.Now(),
+				"content":   content,
+				"medias":    medias,
+				"tags":      entity.ParseTags(content),
+				"mentions":  entity.ParseMentions(content),
+				"updatedAt": time.Now(),
 			},
 		})
 	if err != nil {
@@ -269,115 +582,28 @@ func (p *Repository) PatchFound(ctx context.Context, id string, isResolved bool)
 	return err
 }
 
-func (p *Repository) DeletePost(ctx context.Context, id string) error {
+func (p *Repository) PatchVisibility(ctx context.Context, id string, visibility entity.Visibility, audience []string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	_, err = p.postCollection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
-}
-
-// Comment
-
-func (p *Repository) GetComments(ctx context.Context, postId string) ([]entity.Comment, error) {
-	postOID, err := primitive.ObjectIDFromHex(postId)
-	if err != nil {
-		return nil, err
-	}
-
-	var result struct {
-		Comments []entity.Comment `bson:"comments"`
-	}
-	err = p.postCollection.FindOne(ctx, bson.M{"_id": postOID}, options.FindOne().SetProjection(bson.M{"comments": 1})).Decode(&result)
-	if err != nil {
-		return nil, err
-	}
-	return result.Comments, nil
-}
-
-func (p *Repository) CreateComment(ctx context.Context, postId, username, content string) error {
-	postOID, err := primitive.ObjectIDFromHex(postId)
-	if err != nil {
-		return err
-	}
-	_, err = p.postCollection.UpdateOne(ctx, bson.M{"_id": postOID}, bson.M{
-		"$push": bson.M{
-			"comments": bson.M{
-				"username":  username,
-				"content":   content,
-				"createdAt": time.Now(),
-			},
-		},
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (p *Repository) DeleteComment(ctx context.Context, postId, username string) error {
-	postOID, err := primitive.ObjectIDFromHex(postId)
-	if err != nil {
-		return err
-	}
-	_, err = p.postCollection.UpdateOne(ctx, bson.M{"_id": postOID}, bson.M{
-		"$pull": bson.M{
-			"comments": bson.M{
-				"username": username,
-			},
-		},
-	})
-	return err
-}
-
-// Interaction
-
-func (p *Repository) UpsertInteraction(ctx context.Context, postId, username string, itype entity.InteractionType) error {
-	postOID, err := primitive.ObjectIDFromHex(postId)
-	if err != nil {
-		return err
-	}
-	exists, err := p.updateInteraction(ctx, postOID, username, itype)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return nil
+	set := bson.M{"visibility": visibility, "updatedAt": time.Now()}
+	update := bson.M{"$set": set}
+	if len(audience) > 0 {
+		set["audience"] = audience
+	} else {
+		update["$unset"] = bson.M{"audience": ""}
 	}
-
-	_, err = p.postCollection.UpdateOne(ctx, bson.M{"_id": postOID}, bson.M{
-		"$push": bson.M{
-			"interactions": bson.M{
-				"username": username,
-				"type":     itype,
-			},
-		},
-	})
+	_, err = p.postCollection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
 	return err
 }
 
-func (p *Repository) updateInteraction(ctx context.Context, id primitive.ObjectID, username string, itype entity.InteractionType) (bool, error) {
-	result, err := p.postCollection.UpdateOne(ctx, bson.M{"_id": id, "interactions.username": username}, bson.M{
-		"$set": bson.M{"interactions.$.type": itype},
-	})
-	if err != nil {
-		return false, err
-	}
-	return result.MatchedCount > 0, nil
-}
-
-func (p *Repository) DeleteInteraction(ctx context.Context, postId, username string) error {
-	postOID, err := primitive.ObjectIDFromHex(postId)
+func (p *Repository) DeletePost(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	_, err = p.postCollection.UpdateOne(ctx, bson.M{"_id": postOID}, bson.M{
-		"$pull": bson.M{
-			"interactions": bson.M{"username": username},
-		},
-	})
-
+	_, err = p.postCollection.DeleteOne(ctx, bson.M{"_id": objectID})
 	return err
 }
 
@@ -4,6 +4,8 @@ import (
 	"context"
 	"post/api/client"
 	"post/api/handler/post"
+	commentRepo "post/infrastructure/repository/comment"
+	interactionRepo "post/infrastructure/repository/interaction"
 	postRepo "post/infrastructure/repository/post"
 	postService "post/usecase/post"
 
@@ -31,11 +33,13 @@ func makeHandler() *gin.Engine {
 	mongoDB := mongoClient.Database("test")
 
 	postRepo := postRepo.NewRepository(mongoDB)
+	commentRepo := commentRepo.NewRepository(mongoDB)
+	interactionRepo := interactionRepo.NewRepository(mongoDB)
 
 	userClient := client.NewUserClient("http://user:8080")
 	notiClient := client.NewNotiClient("http://noti:8080")
 
-	postService := postService.NewService(postRepo, notiClient)
+	postService := postService.NewService(postRepo, commentRepo, interactionRepo, notiClient, userClient)
 
 	post.MakeHandler(app, postService, userClient)
 
@@ -5,25 +5,39 @@ import (
 	"post/entity"
 	"post/util"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UseCase interface {
-	GetPost(ctx context.Context, id string) (*entity.Post, error)
-	GetNearLostPosts(ctx context.Context, latitude float64, longitude float64) ([]*entity.Post, error)
-	GetParticipatedPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error)
-	GetPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error)
-	GetPostsOfUsers(ctx context.Context, usernames []string, pagination util.Pagination) ([]*entity.Post, error)
+	GetPost(ctx context.Context, id string, viewer entity.Viewer) (*entity.Post, error)
+	GetNearLostPosts(ctx context.Context, latitude float64, longitude float64, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
+	GetParticipatedPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
+	GetPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
+	GetPostsOfUsers(ctx context.Context, usernames []string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
+	GetFeed(ctx context.Context, viewer entity.Viewer, pagination util.FeedPagination) ([]*entity.Post, string, error)
+	GetPostsByTag(ctx context.Context, tag string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
+	GetTrendingTags(ctx context.Context, window time.Duration, limit int64) ([]*entity.TrendingTag, error)
+	SearchPosts(ctx context.Context, search entity.PostSearch, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error)
 	CheckOwnership(ctx context.Context, username, postId string) (bool, error)
-	CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media) (*entity.Post, error)
-	CreateLostPetPost(ctx context.Context, username, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time) (*entity.Post, error)
-	PatchContent(ctx context.Context, id, content string, medias []entity.Media) (*entity.Post, error)
+	CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error)
+	CreateLostPetPost(ctx context.Context, username, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error)
+	PatchContent(ctx context.Context, id, username, content string, medias []entity.Media) error
 	PatchFound(ctx context.Context, id string, found bool) error
+	PatchVisibility(ctx context.Context, id, username string, visibility entity.Visibility, audience []string) error
 	DeletePost(ctx context.Context, id string) error
 
-	CreateComment(ctx context.Context, postId, username, content string) error
-	DeleteComment(ctx context.Context, postId, username string) error
-	GetComments(ctx context.Context, postId string) ([]entity.Comment, error)
+	CreateComment(ctx context.Context, postId, parentId string, viewer entity.Viewer, content string) (*entity.Comment, error)
+	EditComment(ctx context.Context, postId, commentId, username, content string) (*entity.Comment, error)
+	DeleteComment(ctx context.Context, postId, commentId, username string) error
+	GetComments(ctx context.Context, postId string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Comment, error)
+	GetReplies(ctx context.Context, postId, commentId string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Comment, error)
+	UpsertCommentInteraction(ctx context.Context, postId, commentId string, viewer entity.Viewer, itype entity.InteractionType) error
+	DeleteCommentInteraction(ctx context.Context, postId, commentId, username string) error
+	GetViewerCommentInteractions(ctx context.Context, username string, comments []*entity.Comment) (map[primitive.ObjectID]entity.InteractionType, error)
 
-	UpsertInteraction(ctx context.Context, postId, username string, itype entity.InteractionType) error
-	DeleteInteraction(ctx context.Context, postId, username string, itype entity.InteractionType) error
+	UpsertInteraction(ctx context.Context, postId string, viewer entity.Viewer, itype entity.InteractionType) error
+	DeleteInteraction(ctx context.Context, postId, username string) error
+	GetInteractions(ctx context.Context, postId string, itype entity.InteractionType, viewer entity.Viewer, pagination util.Pagination) ([]*entity.PostInteraction, error)
+	GetViewerInteractions(ctx context.Context, username string, posts []*entity.Post) (map[primitive.ObjectID]entity.InteractionType, error)
 }
@@ -3,67 +3,177 @@ package post
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"post/api/client"
+	"post/api/presenter"
 	"post/entity"
+	"post/infrastructure/repository/comment"
+	"post/infrastructure/repository/interaction"
 	"post/infrastructure/repository/post"
 	"post/util"
+	"strings"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrPostNotFound       = errors.New("post not found")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrEmptyComment       = errors.New("comment content is empty")
+	ErrNotCommentAuthor   = errors.New("only the author can change this comment")
+	ErrInvalidInteraction = errors.New("unknown interaction type")
+	ErrReplyTooDeep       = fmt.Errorf("replies cannot be nested more than %d levels deep", entity.MaxCommentDepth)
+	ErrInvalidVisibility  = errors.New("unknown visibility")
+	ErrInvalidAudience    = fmt.Errorf("a custom audience lists between 1 and %d other users", entity.MaxAudienceSize)
+	ErrInvalidSearch      = errors.New("invalid search filters")
+	ErrInvalidPet         = errors.New("pet not found among the author's pets")
 )
 
 type Service struct {
 	postRepo    *post.Repository
-	notiClient  client.NotiClient
-	userService client.UserClient
+	commentRepo *comment.Repository
+	// Post reactions, reactions to comments are kept on the comment
+	interactionRepo *interaction.Repository
+	notiClient      client.NotiClient
+	userClient      client.UserClient
 }
 
-func NewService(postRepo *post.Repository, notiClient client.NotiClient) *Service {
+func NewService(postRepo *post.Repository, commentRepo *comment.Repository, interactionRepo *interaction.Repository, notiClient client.NotiClient, userClient client.UserClient) *Service {
 	return &Service{
-		postRepo:   postRepo,
-		notiClient: notiClient,
+		postRepo:        postRepo,
+		commentRepo:     commentRepo,
+		interactionRepo: interactionRepo,
+		notiClient:      notiClient,
+		userClient:      userClient,
 	}
 }
 
 // Post
 
-func (s *Service) GetPost(ctx context.Context, id string) (*entity.Post, error) {
-	post, err := s.postRepo.GetPost(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	return post, nil
+// Every read goes through the viewer: posts they are not allowed to see, and the ones written by
+// users in a block relation with them, are left out or reported as not found
+
+func (s *Service) GetPost(ctx context.Context, id string, viewer entity.Viewer) (*entity.Post, error) {
+	return s.getPost(ctx, id, viewer)
+}
+
+func (s *Service) GetNearLostPosts(ctx context.Context, latitude float64, longitude float64, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	posts, err := s.postRepo.GetNearLostPosts(ctx, latitude, longitude, viewer, pagination)
+	return posts, err
 }
 
-func (s *Service) GetNearLostPosts(ctx context.Context, latitude float64, longitude float64, pagination util.Pagination) ([]*entity.Post, error) {
-	return s.postRepo.GetNearLostPosts(ctx, latitude, longitude, pagination)
+func (s *Service) GetParticipatedPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	posts, err := s.postRepo.GetParticipatedPostsOfUser(ctx, username, viewer, pagination)
+	return posts, err
 }
 
-func (s *Service) GetParticipatedPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error) {
-	return s.postRepo.GetParticipatedPostsOfUser(ctx, username, pagination)
+func (s *Service) GetPostsOfUser(ctx context.Context, username string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	posts, err := s.postRepo.GetPostsOfUser(ctx, username, viewer, pagination)
+	return posts, err
 }
 
-func (s *Service) GetPostsOfUser(ctx context.Context, username string, pagination util.Pagination) ([]*entity.Post, error) {
-	return s.postRepo.GetPostsOfUser(ctx, username, pagination)
+func (s *Service) GetPostsOfUsers(ctx context.Context, usernames []string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	posts, err := s.postRepo.GetPostsOfUsers(ctx, usernames, viewer, pagination)
+	return posts, err
 }
 
-func (s *Service) GetPostsOfUsers(ctx context.Context, usernames []string, pagination util.Pagination) ([]*entity.Post, error) {
-	return s.postRepo.GetPostsOfUsers(ctx, usernames, pagination)
+func (s *Service) GetPostsByTag(ctx context.Context, tag string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	posts, err := s.postRepo.GetPostsByTag(ctx, entity.NormalizeTag(tag), viewer, pagination)
+	return posts, err
+}
+
+// GetTrendingTags ranks the tags of the public posts written within the window before now
+func (s *Service) GetTrendingTags(ctx context.Context, window time.Duration, limit int64) ([]*entity.TrendingTag, error) {
+	return s.postRepo.GetTrendingTags(ctx, time.Now().Add(-window), limit)
+}
+
+// SearchPosts sorts by relevance unless told otherwise, which needs a query to rank by
+func (s *Service) SearchPosts(ctx context.Context, search entity.PostSearch, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Post, error) {
+	search.Query = strings.TrimSpace(search.Query)
+	switch search.Type {
+	case "", entity.Blog, entity.Lost, entity.Found:
+	default:
+		return nil, fmt.Errorf("%w: unknown post type %q", ErrInvalidSearch, search.Type)
+	}
+	switch search.Sort {
+	case "":
+		search.Sort = entity.SortRelevance
+	case entity.SortRelevance, entity.SortRecent:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidSearch, search.Sort)
+	}
+	if search.From != nil && search.To != nil && search.From.After(*search.To) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidSearch)
+	}
+
+	return s.postRepo.SearchPosts(ctx, search, viewer, pagination)
+}
+
+// GetFeed ranks the posts of the viewer and their friends, and returns the cursor of the next page,
+// which is empty when there is nothing left
+func (s *Service) GetFeed(ctx context.Context, viewer entity.Viewer, pagination util.FeedPagination) ([]*entity.Post, string, error) {
+	authors := append([]string{viewer.Username}, viewer.Friends...)
+	ranked, err := s.postRepo.GetFeed(ctx, authors, viewer, pagination)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if int64(len(ranked)) > pagination.Size {
+		ranked = ranked[:pagination.Size]
+		last := ranked[len(ranked)-1]
+		nextCursor = util.EncodeFeedCursor(util.FeedCursor{AsOf: pagination.AsOf, Score: last.Score, ID: last.ID.Hex()})
+	}
+
+	posts := make([]*entity.Post, len(ranked))
+	for i, r := range ranked {
+		posts[i] = &r.Post
+	}
+	return posts, nextCursor, nil
 }
 
 func (s *Service) CheckOwnership(ctx context.Context, username, postId string) (bool, error) {
 	return s.postRepo.CheckOwnership(ctx, postId, username)
 }
 
-func (s *Service) CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media) (*entity.Post, error) {
-	post, err := s.postRepo.CreateBlogPost(ctx, username, content, medias)
+func (s *Service) CreateBlogPost(ctx context.Context, username, content string, medias []entity.Media, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error) {
+	visibility, audience, err := checkVisibility(username, visibility, audience)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getOwnPet(username, petID); err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.CreateBlogPost(ctx, username, content, medias, visibility, audience, petID)
 	if err != nil {
 		return nil, err
 	}
+	s.notifyMentions(post, nil)
 	return post, nil
 }
 
-func (s *Service) CreateLostPetPost(ctx context.Context, username, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time) (*entity.Post, error) {
+// CreateLostPetPost can start from one of the author's pet profiles, whose photos stand in for the
+// post's medias when none are given
+func (s *Service) CreateLostPetPost(ctx context.Context, username, contactInfo string, postType entity.PostType, content string, medias []entity.Media, area, lastSeen *entity.Location, lostAt *time.Time, visibility entity.Visibility, audience []string, petID *uint) (*entity.Post, error) {
+	visibility, audience, err := checkVisibility(username, visibility, audience)
+	if err != nil {
+		return nil, err
+	}
+	pet, err := s.getOwnPet(username, petID)
+	if err != nil {
+		return nil, err
+	}
+	if pet != nil && len(medias) == 0 {
+		for _, photo := range pet.Photos {
+			medias = append(medias, entity.Media{Type: entity.Image, URL: photo})
+		}
+	}
+
 	if postType != entity.Found && area != nil && len(area.Location.Coordinates) > 0 {
 		address, err := fetchAddress(area.Location.Coordinates[1], area.Location.Coordinates[0])
 		if err != nil {
@@ -80,110 +190,433 @@ func (s *Service) CreateLostPetPost(ctx context.Context, username, contactInfo s
 		lastSeen.Address = address
 	}
 
-	post, err := s.postRepo.CreateLostPetPost(ctx, username, contactInfo, postType, content, medias, area, lastSeen, lostAt)
+	post, err := s.postRepo.CreateLostPetPost(ctx, username, contactInfo, postType, content, medias, area, lastSeen, lostAt, visibility, audience, petID)
 	if err != nil {
 		return nil, err
 	}
+	s.notifyMentions(post, nil)
 	return post, nil
 }
 
-func (s *Service) PatchContent(ctx context.Context, id, content string, medias []entity.Media) error {
-	ok, err := s.postRepo.PatchContent(ctx, id, content, medias)
+// getOwnPet looks up the pet a new post refers to, nil when it refers to none
+func (s *Service) getOwnPet(username string, petID *uint) (*presenter.Pet, error) {
+	if petID == nil {
+		return nil, nil
+	}
+	pets, err := s.userClient.GetPets([]uint{*petID})
+	if err != nil {
+		return nil, err
+	}
+	if len(pets) == 0 || pets[0].Owner != username {
+		return nil, ErrInvalidPet
+	}
+	return pets[0], nil
+}
+
+// PatchContent is only meant for the post's owner. Users mentioned by the new content are
+// notified unless the old content already mentioned them
+func (s *Service) PatchContent(ctx context.Context, id, username, content string, medias []entity.Media) error {
+	post, err := s.getPost(ctx, id, entity.Viewer{Username: username})
 	if err != nil {
+		return err
+	}
+	if _, err := s.postRepo.PatchContent(ctx, id, content, medias); err != nil {
+		return err
+	}
+
+	previous := post.Mentions
+	post.Mentions = entity.ParseMentions(content)
+	s.notifyMentions(post, previous)
+	return nil
+}
+
+// notifyMentions sends post:mention: to the users the post mentions, except the author, those
+// already mentioned before and those who cannot see the post. The post is saved by then, so a
+// failure is only logged
+func (s *Service) notifyMentions(post *entity.Post, previous []string) {
+	if err := s.sendMentions(post, previous); err != nil {
+		fmt.Printf("Failed to notify mentions of post %s: %v\n", post.ID.Hex(), err)
+	}
+}
+
+func (s *Service) sendMentions(post *entity.Post, previous []string) error {
+	skip := map[string]bool{post.Username: true}
+	for _, username := range previous {
+		skip[username] = true
+	}
+	var mentioned []string
+	for _, username := range post.Mentions {
+		if !skip[username] {
+			mentioned = append(mentioned, username)
+		}
+	}
+	if len(mentioned) == 0 || post.Visibility == entity.VisibilityOnlyMe {
 		return nil
 	}
-	if !ok {
+
+	// Mentions are parsed out of free text and may not name a user at all
+	users, err := s.userClient.FindUsers(mentioned)
+	if err != nil {
+		return err
+	}
+
+	var allowed map[string]bool
+	switch post.Visibility {
+	case entity.VisibilityFriends:
+		friends, err := s.userClient.GetFriendUsernames(post.Username)
+		if err != nil {
+			return err
+		}
+		allowed = toSet(friends)
+	case entity.VisibilityCustom:
+		allowed = toSet(post.Audience)
+	}
+
+	receivers := make([]string, 0, len(users))
+	for _, u := range users {
+		if allowed == nil || allowed[u.Username] {
+			receivers = append(receivers, u.Username)
+		}
+	}
+	if len(receivers) == 0 {
 		return nil
 	}
-	return nil
+	return s.notiClient.CreateNotiToUsers(post.Username, receivers, "post", "post:mention:"+post.Username, post.ID.Hex())
 }
 
-func (s *Service) PatchLostFoundStatus(ctx context.Context, id string, isResolved bool) error {
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	return set
+}
+
+// PatchVisibility is only meant for the post's owner, the audience is kept for custom visibility only
+func (s *Service) PatchVisibility(ctx context.Context, id, username string, visibility entity.Visibility, audience []string) error {
+	if visibility == "" {
+		return ErrInvalidVisibility
+	}
+	visibility, audience, err := checkVisibility(username, visibility, audience)
+	if err != nil {
+		return err
+	}
+	return s.postRepo.PatchVisibility(ctx, id, visibility, audience)
+}
+
+// checkVisibility defaults to public, which is also what lost and found posts are expected to use
+// so the whole neighbourhood can help. The audience is deduplicated and never lists the author
+func checkVisibility(username string, visibility entity.Visibility, audience []string) (entity.Visibility, []string, error) {
+	if visibility == "" {
+		visibility = entity.VisibilityPublic
+	}
+	if !visibility.IsValid() {
+		return "", nil, ErrInvalidVisibility
+	}
+	if visibility != entity.VisibilityCustom {
+		return visibility, nil, nil
+	}
+
+	seen := map[string]bool{username: true}
+	members := make([]string, 0, len(audience))
+	for _, member := range audience {
+		if member == "" || seen[member] {
+			continue
+		}
+		seen[member] = true
+		members = append(members, member)
+	}
+	if len(members) == 0 || len(members) > entity.MaxAudienceSize {
+		return "", nil, ErrInvalidAudience
+	}
+	return visibility, members, nil
+}
+
+// PatchLostFoundStatus is only meant for the post's owner, who always sees their own post
+func (s *Service) PatchLostFoundStatus(ctx context.Context, id, username string, isResolved bool) error {
 	if err := s.postRepo.PatchFound(ctx, id, isResolved); err != nil {
 		return err
 	}
-	post, err := s.postRepo.GetPost(ctx, id)
+	post, err := s.postRepo.GetPost(ctx, id, entity.Viewer{Username: username})
 	if err != nil {
 		return err
 	}
-	return s.notiClient.CreateNotiToUsers(post.Participants, "post", "post:resolved:"+post.Username, id)
+	return s.notiClient.CreateNotiToUsers(post.Username, post.Participants, "post", "post:resolved:"+post.Username, id)
 }
 
 func (s *Service) DeletePost(ctx context.Context, id string) error {
-	return s.postRepo.DeletePost(ctx, id)
+	if err := s.postRepo.DeletePost(ctx, id); err != nil {
+		return err
+	}
+	if err := s.interactionRepo.DeleteInteractionsOfPost(ctx, id); err != nil {
+		return err
+	}
+	return s.commentRepo.DeleteCommentsOfPost(ctx, id)
 }
 
 // Comment
 
-func (s *Service) GetComments(ctx context.Context, postId string) ([]entity.Comment, error) {
-	comments, err := s.postRepo.GetComments(ctx, postId)
+// Comments are read and written only by those who can see the post
+
+func (s *Service) GetComments(ctx context.Context, postId string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Comment, error) {
+	if _, err := s.getPost(ctx, postId, viewer); err != nil {
+		return nil, err
+	}
+	return s.commentRepo.GetComments(ctx, postId, nil, viewer.Hidden, pagination)
+}
+
+func (s *Service) GetReplies(ctx context.Context, postId, commentId string, viewer entity.Viewer, pagination util.Pagination) ([]*entity.Comment, error) {
+	if _, err := s.getPost(ctx, postId, viewer); err != nil {
+		return nil, err
+	}
+	parent, err := s.getComment(ctx, postId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	return s.commentRepo.GetComments(ctx, postId, &parent.ID, viewer.Hidden, pagination)
+}
+
+// CreateComment comments on the post as the viewer, or replies to parentId when it is not empty.
+// The post owner and the author of the parent comment are notified, each at most once. The comment
+// is saved by then, so a failed notification is only logged
+func (s *Service) CreateComment(ctx context.Context, postId, parentId string, viewer entity.Viewer, content string) (*entity.Comment, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyComment
+	}
+	username := viewer.Username
+	post, err := s.getPost(ctx, postId, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *entity.Comment
+	if parentId != "" {
+		parent, err = s.getComment(ctx, postId, parentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Depth() >= entity.MaxCommentDepth {
+			return nil, ErrReplyTooDeep
+		}
+	}
+
+	comment, err := s.commentRepo.CreateComment(ctx, post.ID, parent, username, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if parent != nil && parent.Username != username {
+		s.notify(username, parent.Username, "post:reply:"+username, postId)
+	}
+	if post.Username != username && (parent == nil || parent.Username != post.Username) {
+		s.notify(username, post.Username, "post:comment:"+username, postId)
+	}
+	return comment, nil
+}
+
+// EditComment is only allowed to the comment's author
+func (s *Service) EditComment(ctx context.Context, postId, commentId, username, content string) (*entity.Comment, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyComment
+	}
+	comment, err := s.getComment(ctx, postId, commentId)
 	if err != nil {
 		return nil, err
 	}
-	return comments, nil
+	if comment.Username != username {
+		return nil, ErrNotCommentAuthor
+	}
+	return s.commentRepo.UpdateComment(ctx, comment, content)
 }
 
-func (s *Service) CreateComment(ctx context.Context, postId, username, content string) error {
-	if err := s.postRepo.CreateComment(ctx, postId, username, content); err != nil {
+// DeleteComment is allowed to the comment's author and to the owner of the post
+func (s *Service) DeleteComment(ctx context.Context, postId, commentId, username string) error {
+	comment, err := s.getComment(ctx, postId, commentId)
+	if err != nil {
 		return err
 	}
-	post, err := s.postRepo.GetPost(ctx, postId)
+	if comment.Username != username {
+		isOwner, err := s.postRepo.CheckOwnership(ctx, postId, username)
+		if err != nil {
+			return err
+		}
+		if !isOwner {
+			return ErrNotCommentAuthor
+		}
+	}
+	return s.commentRepo.DeleteComment(ctx, comment)
+}
+
+// UpsertCommentInteraction notifies the comment's author, a failed notification is only logged
+func (s *Service) UpsertCommentInteraction(ctx context.Context, postId, commentId string, viewer entity.Viewer, itype entity.InteractionType) error {
+	if !itype.IsValid() {
+		return ErrInvalidInteraction
+	}
+	if _, err := s.getPost(ctx, postId, viewer); err != nil {
+		return err
+	}
+	username := viewer.Username
+	comment, err := s.getComment(ctx, postId, commentId)
 	if err != nil {
 		return err
 	}
-	if post.Username != username {
-		_, err = s.notiClient.CreateNoti(post.Username, "post", "post:comment:"+username, postId)
+	if err := s.commentRepo.UpsertInteraction(ctx, comment, username, itype); err != nil {
+		return err
 	}
-	return err
+	if comment.Username != username {
+		s.notify(username, comment.Username, "post:commentInteraction:"+username, postId)
+	}
+	return nil
 }
 
-func (s *Service) DeleteComment(ctx context.Context, postId, username string) error {
-	return s.postRepo.DeleteComment(ctx, postId, username)
+func (s *Service) DeleteCommentInteraction(ctx context.Context, postId, commentId, username string) error {
+	comment, err := s.getComment(ctx, postId, commentId)
+	if err != nil {
+		return err
+	}
+	return s.commentRepo.DeleteInteraction(ctx, comment.ID, username)
+}
+
+// GetViewerCommentInteractions maps each of the comments the viewer reacted to onto the reaction
+func (s *Service) GetViewerCommentInteractions(ctx context.Context, username string, comments []*entity.Comment) (map[primitive.ObjectID]entity.InteractionType, error) {
+	commentIDs := make([]primitive.ObjectID, len(comments))
+	for i, c := range comments {
+		commentIDs[i] = c.ID
+	}
+
+	interactions, err := s.commentRepo.GetInteractionsOfUser(ctx, username, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make(map[primitive.ObjectID]entity.InteractionType, len(interactions))
+	for _, i := range interactions {
+		reactions[i.CommentID] = i.Type
+	}
+	return reactions, nil
+}
+
+// notify sends a post notification for a write that is already saved, so a failure is only logged
+func (s *Service) notify(sender, receiver, desc, postId string) {
+	if _, err := s.notiClient.CreateNoti(sender, receiver, "post", desc, postId); err != nil {
+		fmt.Printf("Failed to send %s to %s: %v\n", desc, receiver, err)
+	}
+}
+
+func (s *Service) getComment(ctx context.Context, postId, commentId string) (*entity.Comment, error) {
+	comment, err := s.commentRepo.GetComment(ctx, commentId)
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if comment.PostID.Hex() != postId {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
 }
 
 // Interaction
 
-func (s *Service) UpsertInteraction(ctx context.Context, postId, username string, itype entity.InteractionType) error {
-	if err := s.postRepo.UpsertInteraction(ctx, postId, username, itype); err != nil {
-		return err
+func (s *Service) UpsertInteraction(ctx context.Context, postId string, viewer entity.Viewer, itype entity.InteractionType) error {
+	if !itype.IsValid() {
+		return ErrInvalidInteraction
 	}
-	post, err := s.postRepo.GetPost(ctx, postId)
+	username := viewer.Username
+	post, err := s.getPost(ctx, postId, viewer)
 	if err != nil {
 		return err
 	}
+	if err := s.interactionRepo.UpsertInteraction(ctx, post.ID, username, itype); err != nil {
+		return err
+	}
 	if post.Username != username {
-		_, err = s.notiClient.CreateNoti(post.Username, "post", "post:interaction:"+username, postId)
+		_, err = s.notiClient.CreateNoti(username, post.Username, "post", "post:interaction:"+username, postId)
 	}
 	return err
 }
 
+// DeleteInteraction takes back the user's reaction even when they cannot see the post anymore
 func (s *Service) DeleteInteraction(ctx context.Context, postId, username string) error {
-	return s.postRepo.DeleteInteraction(ctx, postId, username)
+	postID, err := primitive.ObjectIDFromHex(postId)
+	if err != nil {
+		return ErrPostNotFound
+	}
+	return s.interactionRepo.DeleteInteraction(ctx, postID, username)
+}
+
+// GetInteractions lists who reacted to the post, only with itype when it is not empty
+func (s *Service) GetInteractions(ctx context.Context, postId string, itype entity.InteractionType, viewer entity.Viewer, pagination util.Pagination) ([]*entity.PostInteraction, error) {
+	if itype != "" && !itype.IsValid() {
+		return nil, ErrInvalidInteraction
+	}
+	post, err := s.getPost(ctx, postId, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.interactionRepo.GetInteractions(ctx, post.ID, itype, viewer.Hidden, pagination)
+}
+
+// GetViewerInteractions maps each of the posts the viewer reacted to onto the reaction
+func (s *Service) GetViewerInteractions(ctx context.Context, username string, posts []*entity.Post) (map[primitive.ObjectID]entity.InteractionType, error) {
+	postIDs := make([]primitive.ObjectID, len(posts))
+	for i, p := range posts {
+		postIDs[i] = p.ID
+	}
+
+	interactions, err := s.interactionRepo.GetInteractionsOfUser(ctx, username, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make(map[primitive.ObjectID]entity.InteractionType, len(interactions))
+	for _, i := range interactions {
+		reactions[i.PostID] = i.Type
+	}
+	return reactions, nil
+}
+
+func (s *Service) getPost(ctx context.Context, postId string, viewer entity.Viewer) (*entity.Post, error) {
+	post, err := s.postRepo.GetPost(ctx, postId, viewer)
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
 }
 
 // Participation
 
-func (s *Service) Participate(ctx context.Context, postId, username string) error {
-	if err := s.postRepo.Participate(ctx, postId, username); err != nil {
+func (s *Service) Participate(ctx context.Context, postId string, viewer entity.Viewer) error {
+	username := viewer.Username
+	post, err := s.getPost(ctx, postId, viewer)
+	if err != nil {
 		return err
 	}
-	post, err := s.postRepo.GetPost(ctx, postId)
-	if err != nil {
+	if err := s.postRepo.Participate(ctx, postId, username); err != nil {
 		return err
 	}
-	_, err = s.notiClient.CreateNoti(post.Username, "post", "post:participate:"+username, postId)
+	_, err = s.notiClient.CreateNoti(username, post.Username, "post", "post:participate:"+username, postId)
 	return err
 }
 
-func (s *Service) Unparticipate(ctx context.Context, postId, username string) error {
+// Unparticipate always lets the user leave, the owner is only told when the user can still see the post
+func (s *Service) Unparticipate(ctx context.Context, postId string, viewer entity.Viewer) error {
+	username := viewer.Username
 	if err := s.postRepo.Unparticipate(ctx, postId, username); err != nil {
 		return err
 	}
-	post, err := s.postRepo.GetPost(ctx, postId)
+	post, err := s.getPost(ctx, postId, viewer)
+	if errors.Is(err, ErrPostNotFound) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	_, err = s.notiClient.CreateNoti(post.Username, "post", "post:unparticipate:"+username, postId)
+	_, err = s.notiClient.CreateNoti(username, post.Username, "post", "post:unparticipate:"+username, postId)
 	return err
 }
 
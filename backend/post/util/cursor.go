@@ -0,0 +1,77 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeedCursor points at a post of a ranked feed by its (score, id) key. Scores depend on the post's
+// age, so they are all computed as of the time the first page was read
+type FeedCursor struct {
+	AsOf  time.Time
+	Score float64
+	ID    string
+}
+
+type FeedPagination struct {
+	AsOf   time.Time
+	Cursor *FeedCursor
+	Size   int64
+}
+
+func EncodeFeedCursor(c FeedCursor) string {
+	raw := c.AsOf.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatFloat(c.Score, 'g', -1, 64) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeFeedCursor(s string) (*FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &FeedCursor{AsOf: asOf, Score: score, ID: parts[2]}, nil
+}
+
+// ExtractFeedPagination reads `cursor` and `size` from the query, without a cursor the feed
+// starts from the top as of now
+func ExtractFeedPagination(ctx *gin.Context) (FeedPagination, error) {
+	size, err := strconv.ParseInt(ctx.Query("size"), 10, 64)
+	if err != nil || size <= 0 {
+		size = 10
+	}
+	if size > 50 {
+		size = 50
+	}
+
+	pagination := FeedPagination{AsOf: time.Now(), Size: size}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		pagination.Cursor, err = DecodeFeedCursor(cursor)
+		if err != nil {
+			return pagination, err
+		}
+		pagination.AsOf = pagination.Cursor.AsOf
+	}
+
+	return pagination, nil
+}
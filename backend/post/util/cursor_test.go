@@ -0,0 +1,53 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor FeedCursor
+	}{
+		{"plain", FeedCursor{AsOf: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Score: 1.5, ID: "65e1f0c2a4b3c2d1e0f9a8b7"}},
+		{"nanoseconds", FeedCursor{AsOf: time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC), Score: 0.000123456789, ID: "a"}},
+		{"negative score", FeedCursor{AsOf: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Score: -42, ID: "a"}},
+		{"other zone", FeedCursor{AsOf: time.Date(2024, 3, 1, 19, 0, 0, 0, time.FixedZone("ICT", 7*3600)), Score: 3, ID: "a"}},
+		{"separator in id", FeedCursor{AsOf: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Score: 3, ID: "a|b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeFeedCursor(EncodeFeedCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("DecodeFeedCursor: %v", err)
+			}
+			if !got.AsOf.Equal(tt.cursor.AsOf) || got.Score != tt.cursor.Score || got.ID != tt.cursor.ID {
+				t.Fatalf("got %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeFeedCursorRejects(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"missing id", encode("2024-03-01T12:00:00Z|1")},
+		{"bad time", encode("yesterday|1|a")},
+		{"bad score", encode("2024-03-01T12:00:00Z|high|a")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeFeedCursor(tt.cursor); err == nil {
+				t.Fatalf("got %+v, want an error", *got)
+			}
+		})
+	}
+}
@@ -44,3 +44,28 @@ type FriendWrapper struct {
 type SenderWrapper struct {
 	Sender string `json:"sender"`
 }
+
+type BlockWrapper struct {
+	Username string `json:"username"`
+}
+
+type DeviceKeyRequest struct {
+	DeviceID        string `json:"deviceId"`
+	IdentityKey     string `json:"identityKey"`
+	PreKey          string `json:"preKey"`
+	PreKeySignature string `json:"preKeySignature"`
+}
+
+type PetRequest struct {
+	Name        string   `json:"name"`
+	Species     string   `json:"species"`
+	Breed       string   `json:"breed"`
+	Color       string   `json:"color"`
+	Markings    string   `json:"markings"`
+	MicrochipID string   `json:"microchipId"`
+	Photos      []string `json:"photos"`
+}
+
+type PetListRequest struct {
+	IDs []uint `json:"ids"`
+}
@@ -2,11 +2,71 @@ package user
 
 import (
 	"user/api/client"
+	"user/api/payload"
 	"user/entity"
 	"user/presenter"
 	"user/usecase/friend"
 )
 
+func ListDeviceKeyEntityToPresenter(in []*entity.DeviceKey) []*presenter.DeviceKey {
+	out := make([]*presenter.DeviceKey, len(in))
+	for i, k := range in {
+		out[i] = DeviceKeyEntityToPresenter(k)
+	}
+	return out
+}
+
+func DeviceKeyEntityToPresenter(in *entity.DeviceKey) *presenter.DeviceKey {
+	return &presenter.DeviceKey{
+		DeviceID:        in.DeviceID,
+		IdentityKey:     in.IdentityKey,
+		PreKey:          in.PreKey,
+		PreKeySignature: in.PreKeySignature,
+	}
+}
+
+func ListPetEntityToPresenter(in []*entity.Pet, viewer string) []*presenter.Pet {
+	out := make([]*presenter.Pet, len(in))
+	for i, p := range in {
+		out[i] = PetEntityToPresenter(p, viewer)
+	}
+	return out
+}
+
+// PetEntityToPresenter leaves out the microchip ID unless the viewer owns the pet
+func PetEntityToPresenter(in *entity.Pet, viewer string) *presenter.Pet {
+	var microchipID string
+	if viewer != "" && viewer == in.Owner {
+		microchipID = in.MicrochipID
+	}
+
+	return &presenter.Pet{
+		ID:          in.ID,
+		Owner:       in.Owner,
+		Name:        in.Name,
+		Species:     string(in.Species),
+		Breed:       in.Breed,
+		Color:       in.Color,
+		Markings:    in.Markings,
+		MicrochipID: microchipID,
+		Photos:      in.Photos,
+		CreatedAt:   in.CreatedAt,
+		UpdatedAt:   in.UpdatedAt,
+	}
+}
+
+func PetRequestToEntity(in *payload.PetRequest) *entity.Pet {
+	return &entity.Pet{
+		Name:        in.Name,
+		Species:     entity.Species(in.Species),
+		Breed:       in.Breed,
+		Color:       in.Color,
+		Markings:    in.Markings,
+		MicrochipID: in.MicrochipID,
+		Photos:      in.Photos,
+	}
+}
+
 func UserEntityToPresenter(in *entity.User, friendSerivce friend.UseCase) (*presenter.User, error) {
 	friendNum, err := friendSerivce.CountFriends(in.Username)
 	if err != nil {
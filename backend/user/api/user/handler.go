@@ -4,12 +4,14 @@ import (
 	"user/api/client"
 	"user/api/middleware"
 	"user/usecase/friend"
+	"user/usecase/key"
+	"user/usecase/pet"
 	"user/usecase/user"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MakeHandler(app *gin.Engine, userService user.UseCase, friendService friend.UseCase, groupClient client.GroupClient, notiClient client.NotiClient) {
+func MakeHandler(app *gin.Engine, userService user.UseCase, friendService friend.UseCase, keyService key.UseCase, petService pet.UseCase, groupClient client.GroupClient, notiClient client.NotiClient) {
 	userGroup := app.Group("/api/user")
 	{
 		// Public routes
@@ -25,6 +27,15 @@ func MakeHandler(app *gin.Engine, userService user.UseCase, friendService friend
 			CreateUser(c, userService, friendService)
 		})
 
+		// Signed in owners also get the private fields of their pets
+		userGroup.GET("/:username/pets", middleware.AuthMiddleware(), func(c *gin.Context) {
+			GetPetsOfUser(c, petService)
+		})
+
+		userGroup.GET("/pets/:petId", middleware.AuthMiddleware(), func(c *gin.Context) {
+			GetPet(c, petService)
+		})
+
 		// Authenticated routes
 		authGroup := userGroup.Group("", middleware.MustAuthMiddleware())
 
@@ -40,6 +51,10 @@ func MakeHandler(app *gin.Engine, userService user.UseCase, friendService friend
 			GetFriendList(c, friendService, groupClient)
 		})
 
+		authGroup.GET("/friends/usernames", func(c *gin.Context) {
+			GetFriendUsernameList(c, friendService)
+		})
+
 		authGroup.GET("/check-friendship/:username", func(c *gin.Context) {
 			CheckFriendship(c, friendService)
 		})
@@ -63,5 +78,56 @@ func MakeHandler(app *gin.Engine, userService user.UseCase, friendService friend
 		authGroup.DELETE("/friend-requests/decline", func(c *gin.Context) {
 			DeleteIncomingFriendRequest(c, userService, friendService, notiClient)
 		})
+
+		authGroup.GET("/blocks", func(c *gin.Context) {
+			GetBlockList(c, friendService)
+		})
+
+		authGroup.POST("/blocks", func(c *gin.Context) {
+			BlockUser(c, userService, friendService)
+		})
+
+		authGroup.DELETE("/blocks", func(c *gin.Context) {
+			UnblockUser(c, friendService)
+		})
+
+		// Public key bundles of devices, for end-to-end encrypted direct messages
+		authGroup.GET("/:username/keys", func(c *gin.Context) {
+			GetDeviceKeys(c, keyService)
+		})
+
+		authGroup.PUT("/keys", func(c *gin.Context) {
+			RegisterDeviceKey(c, keyService)
+		})
+
+		authGroup.DELETE("/keys/:deviceId", func(c *gin.Context) {
+			RemoveDeviceKey(c, keyService)
+		})
+
+		authGroup.POST("/pets", func(c *gin.Context) {
+			CreatePet(c, petService)
+		})
+
+		authGroup.PUT("/pets/:petId", func(c *gin.Context) {
+			UpdatePet(c, petService)
+		})
+
+		authGroup.DELETE("/pets/:petId", func(c *gin.Context) {
+			DeletePet(c, petService)
+		})
+	}
+
+	// Routes for the other services only, the gateway does not proxy /internal
+	internalGroup := app.Group("/internal/user")
+	{
+		// Pets by id, for the other services to resolve the pets their records refer to
+		internalGroup.POST("/pets/list", func(c *gin.Context) {
+			GetPetList(c, petService)
+		})
+
+		// Users who blocked or were blocked by the requesting user, for the other services to filter with
+		internalGroup.GET("/blocks/related", middleware.MustAuthMiddleware(), func(c *gin.Context) {
+			GetBlockRelations(c, friendService)
+		})
 	}
 }
@@ -1,10 +1,14 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"user/api/client"
 	"user/api/payload"
 	"user/usecase/friend"
+	"user/usecase/key"
+	"user/usecase/pet"
 	"user/usecase/user"
 	"user/util"
 
@@ -115,6 +119,15 @@ func GetFriendList(ctx *gin.Context, Service friend.UseCase, groupClient client.
 	ctx.JSON(http.StatusOK, friendPresenters)
 }
 
+func GetFriendUsernameList(ctx *gin.Context, Service friend.UseCase) {
+	usernames, err := Service.GetFriendUsernames(util.MustGetUsername(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get friend list"})
+		return
+	}
+	ctx.JSON(http.StatusOK, usernames)
+}
+
 func GetFriendRequestList(ctx *gin.Context, Service friend.UseCase) {
 	friendRequests, err := Service.GetFriendRequests(util.MustGetUsername(ctx))
 	if err != nil {
@@ -186,6 +199,10 @@ func SendFriendRequest(ctx *gin.Context, userService user.UseCase, friendService
 	}
 	authUsername := util.MustGetUsername(ctx)
 	frtype, err := friendService.SendFriendRequest(authUsername, body.Receiver)
+	if errors.Is(err, friend.ErrBlocked) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send friend request"})
 		return
@@ -197,14 +214,14 @@ func SendFriendRequest(ctx *gin.Context, userService user.UseCase, friendService
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification - can not get username"})
 			return
 		}
-		notiClient.CreateNoti(body.Receiver, user.Avatar, "friendRequest:send", authUsername)
+		notiClient.CreateNoti(authUsername, body.Receiver, user.Avatar, "friendRequest:send", authUsername)
 	case friend.FriendRequestAccepted:
 		user, err := userService.GetUser(authUsername)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification - can not get username"})
 			return
 		}
-		notiClient.CreateNoti(body.Receiver, user.Avatar, "friendRequest:accepted", authUsername)
+		notiClient.CreateNoti(authUsername, body.Receiver, user.Avatar, "friendRequest:accepted", authUsername)
 	}
 	ctx.JSON(http.StatusOK, nil)
 }
@@ -238,7 +255,7 @@ func DeleteIncomingFriendRequest(ctx *gin.Context, userService user.UseCase, fri
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification - can not get username"})
 		return
 	}
-	notiClient.CreateNoti(body.Sender, user.Avatar, "friendRequest:declined", authUsername)
+	notiClient.CreateNoti(authUsername, body.Sender, user.Avatar, "friendRequest:declined", authUsername)
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
@@ -254,3 +271,209 @@ func DeleteFriend(ctx *gin.Context, Service friend.UseCase) {
 	}
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+func GetBlockList(ctx *gin.Context, Service friend.UseCase) {
+	users, err := Service.GetBlockedUsers(util.MustGetUsername(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get block list"})
+		return
+	}
+	userPresenters, err := ListUserEntityToPresenter(users, Service)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse block list"})
+		return
+	}
+	ctx.JSON(http.StatusOK, userPresenters)
+}
+
+func GetBlockRelations(ctx *gin.Context, Service friend.UseCase) {
+	usernames, err := Service.GetBlockRelations(util.MustGetUsername(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get block relations"})
+		return
+	}
+	ctx.JSON(http.StatusOK, usernames)
+}
+
+func BlockUser(ctx *gin.Context, userService user.UseCase, friendService friend.UseCase) {
+	var body payload.BlockWrapper
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if _, err := userService.GetUser(body.Username); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	err := friendService.BlockUser(util.MustGetUsername(ctx), body.Username)
+	if errors.Is(err, friend.ErrBlockSelf) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func UnblockUser(ctx *gin.Context, Service friend.UseCase) {
+	var body payload.BlockWrapper
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := Service.UnblockUser(util.MustGetUsername(ctx), body.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func GetDeviceKeys(ctx *gin.Context, Service key.UseCase) {
+	keys, err := Service.GetDeviceKeys(ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get device keys"})
+		return
+	}
+	ctx.JSON(http.StatusOK, ListDeviceKeyEntityToPresenter(keys))
+}
+
+func RegisterDeviceKey(ctx *gin.Context, Service key.UseCase) {
+	var body payload.DeviceKeyRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	deviceKey, err := Service.RegisterDeviceKey(util.MustGetUsername(ctx), body.DeviceID, body.IdentityKey, body.PreKey, body.PreKeySignature)
+	if errors.Is(err, key.ErrInvalidBundle) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, key.ErrTooManyDevices) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device key"})
+		return
+	}
+	ctx.JSON(http.StatusOK, DeviceKeyEntityToPresenter(deviceKey))
+}
+
+func RemoveDeviceKey(ctx *gin.Context, Service key.UseCase) {
+	err := Service.RemoveDeviceKey(util.MustGetUsername(ctx), ctx.Param("deviceId"))
+	if errors.Is(err, key.ErrDeviceNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove device key"})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func GetPet(ctx *gin.Context, Service pet.UseCase) {
+	id, err := strconv.ParseUint(ctx.Param("petId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pet id"})
+		return
+	}
+	p, err := Service.GetPet(uint(id))
+	if errors.Is(err, pet.ErrPetNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pet"})
+		return
+	}
+	viewer, _ := util.GetUsername(ctx)
+	ctx.JSON(http.StatusOK, PetEntityToPresenter(p, viewer))
+}
+
+func GetPetsOfUser(ctx *gin.Context, Service pet.UseCase) {
+	pets, err := Service.GetPetsOfUser(ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pets"})
+		return
+	}
+	viewer, _ := util.GetUsername(ctx)
+	ctx.JSON(http.StatusOK, ListPetEntityToPresenter(pets, viewer))
+}
+
+func GetPetList(ctx *gin.Context, Service pet.UseCase) {
+	var body payload.PetListRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	pets, err := Service.GetPets(body.IDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pets"})
+		return
+	}
+	// Other services have no viewer, so they never get the private fields
+	ctx.JSON(http.StatusOK, ListPetEntityToPresenter(pets, ""))
+}
+
+func CreatePet(ctx *gin.Context, Service pet.UseCase) {
+	var body payload.PetRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	p, err := Service.CreatePet(util.MustGetUsername(ctx), PetRequestToEntity(&body))
+	if err != nil {
+		ctx.JSON(petErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, PetEntityToPresenter(p, util.MustGetUsername(ctx)))
+}
+
+func UpdatePet(ctx *gin.Context, Service pet.UseCase) {
+	id, err := strconv.ParseUint(ctx.Param("petId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pet id"})
+		return
+	}
+	var body payload.PetRequest
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	p, err := Service.UpdatePet(util.MustGetUsername(ctx), uint(id), PetRequestToEntity(&body))
+	if err != nil {
+		ctx.JSON(petErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, PetEntityToPresenter(p, util.MustGetUsername(ctx)))
+}
+
+func DeletePet(ctx *gin.Context, Service pet.UseCase) {
+	id, err := strconv.ParseUint(ctx.Param("petId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pet id"})
+		return
+	}
+	if err := Service.DeletePet(util.MustGetUsername(ctx), uint(id)); err != nil {
+		ctx.JSON(petErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func petErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pet.ErrInvalidPet):
+		return http.StatusBadRequest
+	case errors.Is(err, pet.ErrNotPetOwner):
+		return http.StatusForbidden
+	case errors.Is(err, pet.ErrPetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, pet.ErrTooManyPets):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
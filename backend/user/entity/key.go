@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"encoding/base64"
+	"time"
+)
+
+// MaxDeviceIDLength matches the size of the device_id column
+const MaxDeviceIDLength = 64
+
+// DeviceKey is the public key bundle a device publishes for end-to-end encrypted direct messages.
+// Keys are base64 encoded: an Ed25519 identity key and an X25519 pre-key signed by it
+type DeviceKey struct {
+	Username        string `gorm:"PrimaryKey"`
+	DeviceID        string `gorm:"PrimaryKey;size:64"`
+	IdentityKey     string
+	PreKey          string
+	PreKeySignature string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Verify reports whether the bundle is well formed and its pre-key is signed by its identity key,
+// which is all the server can tell about it
+func (k *DeviceKey) Verify() bool {
+	if k.DeviceID == "" || len(k.DeviceID) > MaxDeviceIDLength {
+		return false
+	}
+	identity, err := base64.StdEncoding.DecodeString(k.IdentityKey)
+	if err != nil || len(identity) != ed25519.PublicKeySize {
+		return false
+	}
+	preKey, err := base64.StdEncoding.DecodeString(k.PreKey)
+	if err != nil {
+		return false
+	}
+	if _, err := ecdh.X25519().NewPublicKey(preKey); err != nil {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(k.PreKeySignature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(identity, preKey, signature)
+}
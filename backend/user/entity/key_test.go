@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func signedDeviceKey(t *testing.T) *DeviceKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	preKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	preKeyBytes := preKey.PublicKey().Bytes()
+	return &DeviceKey{
+		Username:        "alice",
+		DeviceID:        "phone",
+		IdentityKey:     base64.StdEncoding.EncodeToString(public),
+		PreKey:          base64.StdEncoding.EncodeToString(preKeyBytes),
+		PreKeySignature: base64.StdEncoding.EncodeToString(ed25519.Sign(private, preKeyBytes)),
+	}
+}
+
+func TestDeviceKeyVerify(t *testing.T) {
+	other := signedDeviceKey(t)
+
+	tests := []struct {
+		name   string
+		mutate func(k *DeviceKey)
+		want   bool
+	}{
+		{"signed", func(k *DeviceKey) {}, true},
+		{"longest device id", func(k *DeviceKey) { k.DeviceID = strings.Repeat("d", MaxDeviceIDLength) }, true},
+		{"swapped pre-key", func(k *DeviceKey) { k.PreKey = other.PreKey }, false},
+		{"other identity", func(k *DeviceKey) { k.IdentityKey = other.IdentityKey }, false},
+		{"flipped signature", func(k *DeviceKey) {
+			signature, _ := base64.StdEncoding.DecodeString(k.PreKeySignature)
+			signature[0] ^= 1
+			k.PreKeySignature = base64.StdEncoding.EncodeToString(signature)
+		}, false},
+		{"short signature", func(k *DeviceKey) { k.PreKeySignature = k.PreKeySignature[:40] }, false},
+		{"short identity key", func(k *DeviceKey) { k.IdentityKey = k.IdentityKey[:20] }, false},
+		{"short pre-key", func(k *DeviceKey) { k.PreKey = "AAAA" }, false},
+		{"not base64", func(k *DeviceKey) { k.IdentityKey = "%%%" }, false},
+		{"no device id", func(k *DeviceKey) { k.DeviceID = "" }, false},
+		{"long device id", func(k *DeviceKey) { k.DeviceID = strings.Repeat("d", MaxDeviceIDLength+1) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := signedDeviceKey(t)
+			tt.mutate(k)
+			if got := k.Verify(); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
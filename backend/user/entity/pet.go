@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+type Species string
+
+const (
+	Dog          Species = "dog"
+	Cat          Species = "cat"
+	Bird         Species = "bird"
+	Rabbit       Species = "rabbit"
+	Hamster      Species = "hamster"
+	Fish         Species = "fish"
+	Reptile      Species = "reptile"
+	OtherSpecies Species = "other"
+)
+
+var SpeciesList = []Species{Dog, Cat, Bird, Rabbit, Hamster, Fish, Reptile, OtherSpecies}
+
+func (s Species) IsValid() bool {
+	for _, species := range SpeciesList {
+		if s == species {
+			return true
+		}
+	}
+	return false
+}
+
+// Pet is an animal profile kept by its owner. Posts refer to it by ID, so a lost report can
+// describe the animal without retyping it
+type Pet struct {
+	ID          uint   `gorm:"PrimaryKey"`
+	Owner       string `gorm:"index"`
+	Name        string
+	Species     Species
+	Breed       string
+	Color       string
+	Markings    string
+	MicrochipID string
+	Photos      []string `gorm:"serializer:json"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
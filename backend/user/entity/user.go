@@ -15,3 +15,10 @@ type FriendRequest struct {
 	Receiver  string `gorm:"PrimaryKey"`
 	CreatedAt time.Time
 }
+
+// Block hides the blocked user from the blocker: no friend requests, direct messages, feed entries or notifications
+type Block struct {
+	Blocker   string `gorm:"PrimaryKey"`
+	Blocked   string `gorm:"PrimaryKey"`
+	CreatedAt time.Time
+}
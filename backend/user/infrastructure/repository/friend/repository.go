@@ -12,7 +12,7 @@ type Repository struct {
 }
 
 func NewRepository(db *gorm.DB) *Repository {
-	db.AutoMigrate(&entity.User{}, &entity.FriendRequest{})
+	db.AutoMigrate(&entity.User{}, &entity.FriendRequest{}, &entity.Block{})
 	return &Repository{
 		db: db,
 	}
@@ -125,3 +125,61 @@ func (r *Repository) DeleteFriendRequest(senderName string, receiverName string)
 	}
 	return nil
 }
+
+// Block also drops any friendship and pending friend requests between the two users
+func (r *Repository) Block(blocker string, blocked string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO blocks (blocker, blocked, created_at) VALUES (?, ?, NOW()) ON CONFLICT DO NOTHING",
+			blocker, blocked).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM friendship WHERE (username = ? AND friend_name = ?) OR (username = ? AND friend_name = ?)",
+			blocker, blocked, blocked, blocker).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM friend_requests WHERE (sender = ? AND receiver = ?) OR (sender = ? AND receiver = ?)",
+			blocker, blocked, blocked, blocker).Error
+	})
+}
+
+func (r *Repository) Unblock(blocker string, blocked string) error {
+	if err := r.db.Exec("DELETE FROM blocks WHERE blocker = ? AND blocked = ?", blocker, blocked).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) GetBlockedUsers(username string) ([]*entity.User, error) {
+	var users []*entity.User
+	err := r.db.
+		Table("blocks b").
+		Select("u.*").
+		Joins("JOIN users u ON b.blocked = u.username").
+		Where("b.blocker = ?", username).
+		Order("b.created_at DESC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetBlockRelations lists the users who either blocked or were blocked by the given user
+func (r *Repository) GetBlockRelations(username string) ([]string, error) {
+	usernames := []string{}
+	if err := r.db.Raw("SELECT blocked FROM blocks WHERE blocker = ? UNION SELECT blocker FROM blocks WHERE blocked = ?",
+		username, username).Scan(&usernames).Error; err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+func (r *Repository) CheckBlocked(userA string, userB string) (bool, error) {
+	var count int64
+	if err := r.db.Table("blocks").
+		Where("(blocker = ? AND blocked = ?) OR (blocker = ? AND blocked = ?)", userA, userB, userB, userA).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
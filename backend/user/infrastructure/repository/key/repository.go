@@ -0,0 +1,64 @@
+package key
+
+import (
+	"user/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	db.AutoMigrate(&entity.DeviceKey{})
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) GetDeviceKeys(username string) ([]*entity.DeviceKey, error) {
+	keys := []*entity.DeviceKey{}
+	if err := r.db.Where("username = ?", username).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *Repository) CountDeviceKeys(username string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entity.DeviceKey{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) CheckDeviceKey(username string, deviceID string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&entity.DeviceKey{}).
+		Where("username = ? AND device_id = ?", username, deviceID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpsertDeviceKey replaces the bundle of a device that registers again
+func (r *Repository) UpsertDeviceKey(key *entity.DeviceKey) (*entity.DeviceKey, error) {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "username"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"identity_key", "pre_key", "pre_key_signature", "updated_at"}),
+	}).Create(key).Error
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *Repository) DeleteDeviceKey(username string, deviceID string) error {
+	if err := r.db.Where("username = ? AND device_id = ?", username, deviceID).Delete(&entity.DeviceKey{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package pet
+
+import (
+	"user/entity"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	db.AutoMigrate(&entity.Pet{})
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) GetPet(id uint) (*entity.Pet, error) {
+	var pet entity.Pet
+	if err := r.db.First(&pet, id).Error; err != nil {
+		return nil, err
+	}
+	return &pet, nil
+}
+
+func (r *Repository) GetPets(ids []uint) ([]*entity.Pet, error) {
+	pets := []*entity.Pet{}
+	if len(ids) == 0 {
+		return pets, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&pets).Error; err != nil {
+		return nil, err
+	}
+	return pets, nil
+}
+
+func (r *Repository) GetPetsOfOwner(owner string) ([]*entity.Pet, error) {
+	pets := []*entity.Pet{}
+	if err := r.db.Where("owner = ?", owner).Order("created_at").Find(&pets).Error; err != nil {
+		return nil, err
+	}
+	return pets, nil
+}
+
+func (r *Repository) CountPets(owner string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entity.Pet{}).Where("owner = ?", owner).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) CreatePet(pet *entity.Pet) (*entity.Pet, error) {
+	if err := r.db.Create(pet).Error; err != nil {
+		return nil, err
+	}
+	return pet, nil
+}
+
+func (r *Repository) UpdatePet(pet *entity.Pet) (*entity.Pet, error) {
+	if err := r.db.Save(pet).Error; err != nil {
+		return nil, err
+	}
+	return pet, nil
+}
+
+func (r *Repository) DeletePet(id uint) error {
+	if err := r.db.Delete(&entity.Pet{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
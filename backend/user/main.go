@@ -5,8 +5,12 @@ import (
 	"user/api/client"
 	"user/api/user"
 	friendRepo "user/infrastructure/repository/friend"
+	keyRepo "user/infrastructure/repository/key"
+	petRepo "user/infrastructure/repository/pet"
 	userRepo "user/infrastructure/repository/user"
 	friendService "user/usecase/friend"
+	keyService "user/usecase/key"
+	petService "user/usecase/pet"
 	userService "user/usecase/user"
 
 	"github.com/gin-gonic/gin"
@@ -34,14 +38,18 @@ func makeHandler() *gin.Engine {
 
 	userRepo := userRepo.NewRepository(db)
 	friendRepo := friendRepo.NewRepository(db)
+	keyRepo := keyRepo.NewRepository(db)
+	petRepo := petRepo.NewRepository(db)
 
 	friendService := friendService.NewService(friendRepo)
 	userService := userService.NewService(userRepo)
+	keyService := keyService.NewService(keyRepo)
+	petService := petService.NewService(petRepo)
 
 	groupClient := client.NewGroupClient("http://message:8080")
 	notiClient := client.NewNotiClient("http://noti:8080")
 
-	user.MakeHandler(app, userService, friendService, groupClient, notiClient)
+	user.MakeHandler(app, userService, friendService, keyService, petService, groupClient, notiClient)
 
 	return app
 }
@@ -0,0 +1,8 @@
+package presenter
+
+type DeviceKey struct {
+	DeviceID        string `json:"deviceId"`
+	IdentityKey     string `json:"identityKey"`
+	PreKey          string `json:"preKey"`
+	PreKeySignature string `json:"preKeySignature"`
+}
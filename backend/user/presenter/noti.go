@@ -13,6 +13,7 @@ type Notification struct {
 }
 
 type NotiCreateRequest struct {
+	Sender   string `json:"sender"`
 	Username string `json:"username"`
 	Icon     string `json:"icon"`
 	Desc     string `json:"desc"`
@@ -20,6 +21,7 @@ type NotiCreateRequest struct {
 }
 
 type NotiToUsersCreateRequest struct {
+	Sender    string   `json:"sender"`
 	Usernames []string `json:"usernames"`
 	Icon      string   `json:"icon"`
 	Desc      string   `json:"desc"`
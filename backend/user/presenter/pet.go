@@ -0,0 +1,19 @@
+package presenter
+
+import "time"
+
+type Pet struct {
+	ID        uint      `json:"id"`
+	Owner     string    `json:"owner"`
+	Name      string    `json:"name"`
+	Species   string    `json:"species"`
+	Breed     string    `json:"breed"`
+	Color     string    `json:"color"`
+	Markings  string    `json:"markings"`
+	Photos    []string  `json:"photos"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Only shown to the pet's owner
+	MicrochipID string `json:"microchipId,omitempty"`
+}
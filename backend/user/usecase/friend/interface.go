@@ -1,6 +1,14 @@
 package friend
 
-import "user/entity"
+import (
+	"errors"
+	"user/entity"
+)
+
+var (
+	ErrBlocked   = errors.New("user is blocked")
+	ErrBlockSelf = errors.New("can not block yourself")
+)
 
 type FriendRequestResult int
 
@@ -18,7 +26,13 @@ type UseCase interface {
 	CountFriendRequests(username string) (int, error)
 
 	GetFriends(username string) ([]*entity.User, error)
+	GetFriendUsernames(username string) ([]string, error)
 	DeleteFriend(userA string, userBName string) error
 	CheckFriendship(userA string, userBName string) (bool, error)
 	CountFriends(username string) (int, error)
+
+	GetBlockedUsers(username string) ([]*entity.User, error)
+	GetBlockRelations(username string) ([]string, error)
+	BlockUser(blocker string, blocked string) error
+	UnblockUser(blocker string, blocked string) error
 }
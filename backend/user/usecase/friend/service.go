@@ -28,6 +28,14 @@ func (s *Service) CheckFriendRequest(senderName string, receiverName string) (bo
 }
 
 func (s *Service) SendFriendRequest(senderName string, receiverName string) (FriendRequestResult, error) {
+	blocked, err := s.friendRepo.CheckBlocked(senderName, receiverName)
+	if err != nil {
+		return FriendRequestNone, err
+	}
+	if blocked {
+		return FriendRequestNone, ErrBlocked
+	}
+
 	friendExists, err := s.friendRepo.CheckFriendship(senderName, receiverName)
 	if err != nil {
 		return FriendRequestNone, err
@@ -87,6 +95,14 @@ func (s *Service) GetFriends(username string) ([]*entity.User, error) {
 	return friends, nil
 }
 
+func (s *Service) GetFriendUsernames(username string) ([]string, error) {
+	usernames, err := s.friendRepo.GetFriendUsernames(username)
+	if err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
 func (s *Service) CheckFriendship(userA string, userBName string) (bool, error) {
 	return s.friendRepo.CheckFriendship(userA, userBName)
 }
@@ -105,3 +121,33 @@ func (s *Service) CountFriends(username string) (int, error) {
 	}
 	return int(count), nil
 }
+
+func (s *Service) GetBlockedUsers(username string) ([]*entity.User, error) {
+	users, err := s.friendRepo.GetBlockedUsers(username)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *Service) GetBlockRelations(username string) ([]string, error) {
+	usernames, err := s.friendRepo.GetBlockRelations(username)
+	if err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+func (s *Service) BlockUser(blocker string, blocked string) error {
+	if blocker == blocked {
+		return ErrBlockSelf
+	}
+	return s.friendRepo.Block(blocker, blocked)
+}
+
+func (s *Service) UnblockUser(blocker string, blocked string) error {
+	if err := s.friendRepo.Unblock(blocker, blocked); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package key
+
+import (
+	"errors"
+	"user/entity"
+)
+
+var (
+	ErrInvalidBundle  = errors.New("key bundle is malformed or its pre-key signature does not verify")
+	ErrTooManyDevices = errors.New("too many devices registered")
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+type UseCase interface {
+	GetDeviceKeys(username string) ([]*entity.DeviceKey, error)
+	RegisterDeviceKey(username string, deviceID string, identityKey string, preKey string, preKeySignature string) (*entity.DeviceKey, error)
+	RemoveDeviceKey(username string, deviceID string) error
+}
@@ -0,0 +1,67 @@
+package key
+
+import (
+	"user/entity"
+	"user/infrastructure/repository/key"
+)
+
+const maxDevices = 10
+
+type Service struct {
+	keyRepo *key.Repository
+}
+
+func NewService(keyRepo *key.Repository) *Service {
+	return &Service{
+		keyRepo: keyRepo,
+	}
+}
+
+func (s *Service) GetDeviceKeys(username string) ([]*entity.DeviceKey, error) {
+	keys, err := s.keyRepo.GetDeviceKeys(username)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RegisterDeviceKey only accepts bundles that verify, see entity.DeviceKey.Verify
+func (s *Service) RegisterDeviceKey(username string, deviceID string, identityKey string, preKey string, preKeySignature string) (*entity.DeviceKey, error) {
+	deviceKey := &entity.DeviceKey{
+		Username:        username,
+		DeviceID:        deviceID,
+		IdentityKey:     identityKey,
+		PreKey:          preKey,
+		PreKeySignature: preKeySignature,
+	}
+	if !deviceKey.Verify() {
+		return nil, ErrInvalidBundle
+	}
+
+	exists, err := s.keyRepo.CheckDeviceKey(username, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		count, err := s.keyRepo.CountDeviceKeys(username)
+		if err != nil {
+			return nil, err
+		}
+		if count >= maxDevices {
+			return nil, ErrTooManyDevices
+		}
+	}
+
+	return s.keyRepo.UpsertDeviceKey(deviceKey)
+}
+
+func (s *Service) RemoveDeviceKey(username string, deviceID string) error {
+	exists, err := s.keyRepo.CheckDeviceKey(username, deviceID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrDeviceNotFound
+	}
+	return s.keyRepo.DeleteDeviceKey(username, deviceID)
+}
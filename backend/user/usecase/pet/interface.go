@@ -0,0 +1,22 @@
+package pet
+
+import (
+	"errors"
+	"user/entity"
+)
+
+var (
+	ErrPetNotFound = errors.New("pet not found")
+	ErrNotPetOwner = errors.New("only the owner can change this pet")
+	ErrInvalidPet  = errors.New("a pet needs a name and a known species, and its details must fit their limits")
+	ErrTooManyPets = errors.New("too many pets registered")
+)
+
+type UseCase interface {
+	GetPet(id uint) (*entity.Pet, error)
+	GetPets(ids []uint) ([]*entity.Pet, error)
+	GetPetsOfUser(username string) ([]*entity.Pet, error)
+	CreatePet(username string, pet *entity.Pet) (*entity.Pet, error)
+	UpdatePet(username string, id uint, pet *entity.Pet) (*entity.Pet, error)
+	DeletePet(username string, id uint) error
+}
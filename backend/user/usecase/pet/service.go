@@ -0,0 +1,136 @@
+package pet
+
+import (
+	"errors"
+	"strings"
+	"user/entity"
+	"user/infrastructure/repository/pet"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxPets        = 50
+	maxPhotos      = 10
+	maxFieldLength = 100
+	// Markings are free text like "white patch over the left eye"
+	maxMarkingsLength = 500
+)
+
+type Service struct {
+	petRepo *pet.Repository
+}
+
+func NewService(petRepo *pet.Repository) *Service {
+	return &Service{
+		petRepo: petRepo,
+	}
+}
+
+func (s *Service) GetPet(id uint) (*entity.Pet, error) {
+	p, err := s.petRepo.GetPet(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPets leaves out the IDs that do not exist, for the other services to resolve references with
+func (s *Service) GetPets(ids []uint) ([]*entity.Pet, error) {
+	return s.petRepo.GetPets(ids)
+}
+
+func (s *Service) GetPetsOfUser(username string) ([]*entity.Pet, error) {
+	return s.petRepo.GetPetsOfOwner(username)
+}
+
+func (s *Service) CreatePet(username string, p *entity.Pet) (*entity.Pet, error) {
+	if err := normalizePet(p); err != nil {
+		return nil, err
+	}
+
+	count, err := s.petRepo.CountPets(username)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxPets {
+		return nil, ErrTooManyPets
+	}
+
+	p.ID = 0
+	p.Owner = username
+	return s.petRepo.CreatePet(p)
+}
+
+// UpdatePet replaces every detail of the pet, only its owner can do it
+func (s *Service) UpdatePet(username string, id uint, p *entity.Pet) (*entity.Pet, error) {
+	if err := normalizePet(p); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getOwnedPet(username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Name = p.Name
+	existing.Species = p.Species
+	existing.Breed = p.Breed
+	existing.Color = p.Color
+	existing.Markings = p.Markings
+	existing.MicrochipID = p.MicrochipID
+	existing.Photos = p.Photos
+	return s.petRepo.UpdatePet(existing)
+}
+
+// DeletePet only removes the profile, posts that referred to it show no pet anymore
+func (s *Service) DeletePet(username string, id uint) error {
+	if _, err := s.getOwnedPet(username, id); err != nil {
+		return err
+	}
+	return s.petRepo.DeletePet(id)
+}
+
+func (s *Service) getOwnedPet(username string, id uint) (*entity.Pet, error) {
+	p, err := s.GetPet(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Owner != username {
+		return nil, ErrNotPetOwner
+	}
+	return p, nil
+}
+
+func normalizePet(p *entity.Pet) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Species = entity.Species(strings.ToLower(strings.TrimSpace(string(p.Species))))
+	p.Breed = strings.TrimSpace(p.Breed)
+	p.Color = strings.TrimSpace(p.Color)
+	p.Markings = strings.TrimSpace(p.Markings)
+	p.MicrochipID = strings.TrimSpace(p.MicrochipID)
+
+	if p.Name == "" || !p.Species.IsValid() {
+		return ErrInvalidPet
+	}
+	for _, field := range []string{p.Name, p.Breed, p.Color, p.MicrochipID} {
+		if len(field) > maxFieldLength {
+			return ErrInvalidPet
+		}
+	}
+	if len(p.Markings) > maxMarkingsLength || len(p.Photos) > maxPhotos {
+		return ErrInvalidPet
+	}
+
+	photos := make([]string, 0, len(p.Photos))
+	for _, photo := range p.Photos {
+		if photo = strings.TrimSpace(photo); photo != "" {
+			photos = append(photos, photo)
+		}
+	}
+	p.Photos = photos
+	return nil
+}